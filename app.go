@@ -9,26 +9,35 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
-	"time"
 
-	"github.com/go-vgo/robotgo"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // Update the App struct
 type App struct {
-	ctx          context.Context
-	taskQueue    *TaskQueue
-	isExecuting  bool
-	execMutex    sync.Mutex
-	completed    map[string]bool
-	completedMux sync.Mutex
-	dependencies map[string][]string
-	nodeMap      map[string]Node
-	notifyCh     chan string
+	ctx       context.Context
+	driver    InputDriver
+	screen    ScreenCapturer
+	windows   WindowProvider
+	clipboard Clipboard
+	keyState  KeyStateReader
+	hooks     HookSource
+	flows     FlowLoader
+	recording *recording
+	lastFlow  string // flowchart JSON of the last run started
+	// onEvent receives the events emitted without a frontend, for tests
+	onEvent func(event string, payload interface{})
+
+	clipboardMu sync.Mutex // held while a TypeString pastes
+	settingsMu  sync.Mutex
+	settings    Settings
+	hotkeys     *hotkeyListener
+	isExecuting bool
+	execMutex   sync.Mutex
+	run         *ExecutionRun
 }
 
 // Node represents a single node in the flowchart.
@@ -39,17 +48,22 @@ type Node struct {
 	Position map[string]float64     `json:"position"`
 }
 
-// Edge represents a connection between two nodes.
+// Edge represents a connection between two nodes. SourceHandle names the
+// output it leaves from, for nodes with more than one (e.g. a loop's body
+// and exit).
 type Edge struct {
-	ID     string `json:"id"`
-	Source string `json:"source"`
-	Target string `json:"target"`
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	SourceHandle string `json:"sourceHandle,omitempty"`
+	Target       string `json:"target"`
 }
 
-// Flowchart represents the entire flowchart with nodes and edges.
+// Flowchart represents the entire flowchart with nodes and edges, plus the
+// options for the run it is submitted with.
 type Flowchart struct {
-	Nodes []Node `json:"nodes"`
-	Edges []Edge `json:"edges"`
+	Nodes   []Node     `json:"nodes"`
+	Edges   []Edge     `json:"edges"`
+	Options RunOptions `json:"options"`
 }
 
 // FlowData represents the complete flow chart data structure
@@ -65,32 +79,46 @@ type Task struct {
 	Data map[string]interface{}
 }
 
-// TaskQueue manages the queue of tasks to be executed.
-type TaskQueue struct {
-	tasks   chan Task
-	wg      sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
-	started bool
-	mutex   sync.Mutex
-	app     *App
-}
-
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{
-		taskQueue:    NewTaskQueue(nil, 100),
-		completed:    make(map[string]bool),
-		notifyCh:     make(chan string, 100),
-		dependencies: make(map[string][]string),
+	app := NewAppWithDriver(NewRobotgoDriver())
+	app.hooks = newSystemHookSource()
+	app.settings = loadSettings()
+	return app
+}
+
+// NewAppWithDriver creates an App that performs all input through driver,
+// e.g. a RecordingDriver for headless runs.
+func NewAppWithDriver(driver InputDriver) *App {
+	app := &App{
+		driver:   driver,
+		flows:    savedFlows{},
+		settings: defaultSettings(),
+	}
+	// Screen capture is optional; drivers that support it implement ScreenCapturer
+	if screen, ok := driver.(ScreenCapturer); ok {
+		app.screen = screen
 	}
+	if windows, ok := driver.(WindowProvider); ok {
+		app.windows = windows
+	}
+	if clipboard, ok := driver.(Clipboard); ok {
+		app.clipboard = clipboard
+	}
+	if keyState, ok := driver.(KeyStateReader); ok {
+		app.keyState = keyState
+	}
+	return app
 }
 
 // Initialize auth state on startup
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	a.taskQueue.app = a  // Set the app reference in TaskQueue
-	a.taskQueue.Start(3) // Start 3 workers by default
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	if err := a.applyHotkeys(); err != nil {
+		log.Printf("Global hotkeys unavailable: %v", err)
+	}
 }
 
 // SaveFile shows the native Save File dialog and saves the flow data to the selected location.
@@ -108,6 +136,21 @@ func (a *App) SaveFile(flowData FlowData) (string, error) {
 	}
 }
 
+// SaveFlowAs saves the flow data under name in the app data directory, where
+// CallFlowNodes can find it.
+func (a *App) SaveFlowAs(name string, flowData FlowData) (string, error) {
+	if name == "" || filepath.Base(name) != name || name == ".." {
+		return "", fmt.Errorf("invalid flow name %q", name)
+	}
+	path, err := utils.SaveFlowData(flowData, name)
+	if err != nil {
+		log.Printf("Failed to save flow %q: %v", name, err)
+		return "", err
+	}
+	a.emitEvent("save-success", fmt.Sprintf("Flow saved to %s", path))
+	return path, nil
+}
+
 // LoadLastFile attempts to load the last opened file's data
 func (a *App) LoadLastFile() (*FlowData, error) {
 	// Get the last opened file path
@@ -138,688 +181,328 @@ func (a *App) LoadLastFile() (*FlowData, error) {
 
 //=============================================== Flow Execution ===============================================
 
-// NewTaskQueue initializes a new TaskQueue.
-func NewTaskQueue(app *App, bufferSize int) *TaskQueue {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &TaskQueue{
-		tasks:  make(chan Task, bufferSize),
-		ctx:    ctx,
-		cancel: cancel,
-		app:    app,
-	}
-}
-
-// Start initializes worker goroutines to process tasks.
-func (q *TaskQueue) Start(workerCount int) {
-	q.mutex.Lock()
-	defer q.mutex.Unlock()
-	if q.started {
-		return
-	}
-	q.started = true
-	for i := 0; i < workerCount; i++ {
-		q.wg.Add(1)
-		go q.worker(i)
-	}
-	log.Printf("TaskQueue started with %d workers", workerCount)
+// ValidateFlow runs the pre-flight checks StartExecution performs and returns
+// every issue found, so the editor can highlight them before running.
+func (a *App) ValidateFlow(flow FlowData) ValidationReport {
+	return validateFlow(flow)
 }
 
-// worker processes tasks from the queue.
-func (q *TaskQueue) worker(workerID int) {
-	defer q.wg.Done()
-	log.Printf("Worker %d started", workerID)
-	for {
-		select {
-		case task, ok := <-q.tasks:
-			if !ok {
-				log.Printf("Worker %d stopping: task channel closed", workerID)
-				return
-			}
-			log.Printf("Worker %d processing task %s of type %s", workerID, task.ID, task.Type)
-			q.app.emitEvent("task-started", task.ID)
-			executeTask(task, q.app)
-			q.app.emitEvent("task-completed", task.ID)
-			// Notify task completion for dependency handling
-			q.app.notifyTaskCompletion(task.ID)
-		case <-q.ctx.Done():
-			log.Printf("Worker %d stopping: context canceled", workerID)
-			return
-		}
-	}
-}
+// StartExecution receives the flowchart data and starts execution. It returns
+// the ID of the new run, which is included in every event the run emits.
+func (a *App) StartExecution(flow string) (runID string, err error) {
+	a.execMutex.Lock()
+	defer a.execMutex.Unlock()
 
-// Enqueue adds a task to the queue.
-func (q *TaskQueue) Enqueue(task Task) {
-	select {
-	case q.tasks <- task:
-		log.Printf("Enqueued task %s of type %s", task.ID, task.Type)
-	case <-q.ctx.Done():
-		log.Println("Task queue is closed. Cannot enqueue task:", task.ID)
+	if a.isExecuting {
+		log.Println("StartExecution called but execution is already in progress")
+		return "", errors.New("execution already in progress")
 	}
-}
-
-// Stop gracefully shuts down the task queue.
-func (q *TaskQueue) Stop() {
-	q.mutex.Lock()
-	defer q.mutex.Unlock()
-	if !q.started {
-		return
+	if a.recording != nil {
+		return "", errors.New("stop recording before running a flow")
 	}
-	q.cancel()
-	close(q.tasks)
-	q.wg.Wait()
-	q.started = false
-	log.Println("TaskQueue has been stopped")
-}
-
-type Point struct {
-	X, Y float64
-}
-
-// executeTask performs the action based on the task type.
-func executeTask(task Task, app *App) {
-	log.Printf("Starting execution of task ID: %s, Type: %s", task.ID, task.Type)
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Recovered from panic in task %s: %v", task.ID, r)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  fmt.Sprintf("panic: %v", r),
+			a.isExecuting = false
+			a.run = nil
+			log.Printf("Recovered from panic in StartExecution: %v", r)
+			a.emitEvent("execution-error", map[string]interface{}{
+				"runId": runID,
+				"error": fmt.Sprintf("panic: %v", r),
 			})
+			err = fmt.Errorf("panic: %v", r)
 		}
-		log.Printf("Completed execution of task ID: %s", task.ID)
 	}()
 
-	switch task.Type {
-	case "StartNode":
-		log.Printf("Flow Started - Task ID: %s", task.ID)
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "StartNode",
-		})
-
-	case "MouseMoveNode":
-		log.Printf("MoveMouse task starting - Data: %+v", task.Data)
-
-		// Extract and validate position configurations
-		startPos, ok1 := task.Data["startPosition"].(map[string]interface{})
-		endPos, ok2 := task.Data["endPosition"].(map[string]interface{})
-		if !ok1 || !ok2 {
-			err := "Invalid or missing position configurations"
-			log.Printf("MoveMouse error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
-
-		// Get current mouse position for 'Mouse' type positions
-		currentX, currentY := robotgo.Location()
-
-		// Resolve start position
-		var startX, startY float64
-		if startPos["type"] == "Mouse" {
-			startX, startY = float64(currentX), float64(currentY)
-		} else {
-			coords, ok := startPos["coordinates"].(map[string]interface{})
-			if !ok {
-				err := "Invalid start coordinates"
-				log.Printf("MoveMouse error: %s for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  err,
-				})
-				return
-			}
-			startX = coords["x"].(float64)
-			startY = coords["y"].(float64)
-		}
-
-		// Move to start position if not already there
-		robotgo.Move(int(startX), int(startY))
-
-		// Resolve end position
-		var endX, endY float64
-		if endPos["type"] == "Mouse" {
-			endX, endY = float64(currentX), float64(currentY)
-		} else {
-			coords, ok := endPos["coordinates"].(map[string]interface{})
-			if !ok {
-				err := "Invalid end coordinates"
-				log.Printf("MoveMouse error: %s for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  err,
-				})
-				return
-			}
-			endX = coords["x"].(float64)
-			endY = coords["y"].(float64)
-		}
-
-		// Extract movement settings
-		speed := task.Data["speed"].(map[string]interface{})
-		speedType := speed["type"].(string)
-		speedValue := speed["value"].(float64)
-		randomize := speed["randomize"].(bool)
-		variance := speed["variance"].(float64)
-		pathType := task.Data["pathType"].(string)
-		dragWhileMoving := task.Data["dragWhileMoving"].(bool)
-
-		// Calculate final speed with randomization if enabled
-		finalSpeed := speedValue
-		if randomize {
-			r := rand.New(rand.NewSource(time.Now().UnixNano()))
-			varianceAmount := speedValue * (variance / 100.0)
-			finalSpeed += (r.Float64()*2 - 1) * varianceAmount
-		}
-
-		// Set mouse movement speed based on configuration
-		robotgo.MouseSleep = int(finalSpeed) // Convert to appropriate sleep value
-
-		// Start drag if required
-		if dragWhileMoving {
-			if err := robotgo.MouseDown("left"); err != nil {
-				log.Printf("MouseDown error: %v for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  fmt.Sprintf("MouseDown failed: %v", err),
-				})
-				return
-			}
-		}
-
-		// Execute movement based on configuration
-		if speedType == "Instant" {
-			robotgo.Move(int(endX), int(endY))
-		} else {
-			mouseDelay := int(finalSpeed)
-			if pathType == "Straight" {
-				// Use MoveSmooth with minimal randomization for straight path TODO: use relative?
-				success := robotgo.MoveSmooth(int(endX), int(endY), 1.0, 1.2, mouseDelay)
-				if !success {
-					log.Printf("MoveSmooth failed for task %s", task.ID)
-					// Fallback to regular move
-					robotgo.Move(int(endX), int(endY))
-				}
-			} else {
-				// Use MoveSmooth with more randomization for human-like movement
-				success := robotgo.MoveSmooth(int(endX), int(endY), 1.0, 2.0, mouseDelay)
-				if !success {
-					log.Printf("MoveSmooth failed for task %s", task.ID)
-					// Fallback to regular move
-					robotgo.Move(int(endX), int(endY))
-				}
-			}
-		}
+	var flowchart Flowchart
+	if err := json.Unmarshal([]byte(flow), &flowchart); err != nil {
+		log.Printf("Failed to unmarshal flowchart: %v", err)
+		return "", fmt.Errorf("invalid flowchart data: %w", err)
+	}
 
-		// Release drag if active
-		if dragWhileMoving {
-			if err := robotgo.MouseUp("left"); err != nil {
-				log.Printf("MouseUp error: %v for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  fmt.Sprintf("MouseUp failed: %v", err),
-				})
-				return
-			}
-		}
+	// Validate flowchart
+	report := validateFlow(FlowData{Nodes: flowchart.Nodes, Edges: flowchart.Edges})
+	for _, issue := range report.Issues {
+		log.Printf("Flowchart validation %s [%s]: %s", issue.Severity, issue.Code, issue.Message)
+	}
+	if err := report.Err(); err != nil {
+		return "", err
+	}
 
-		// Reset mouse sleep to default
-		robotgo.MouseSleep = 100
-
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "MoveMouse",
-		})
-
-	case "MouseClickNode":
-		log.Printf("Click task starting - Data: %+v", task.Data)
-
-		// Get buttonType
-		buttonType, ok := task.Data["buttonType"].(string)
-		if !ok {
-			err := fmt.Sprintf("Invalid buttonType value: %v", task.Data["buttonType"])
-			log.Printf("Click error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
+	options := flowchart.Options.withDefaults()
+	if err := options.validate(); err != nil {
+		return "", err
+	}
 
-		// Map buttonType to robotgo button
-		if buttonType != "left" && buttonType != "right" && buttonType != "middle" {
-			err := fmt.Sprintf("Unsupported buttonType: %s", buttonType)
-			log.Printf("Click error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
+	run, err := newExecutionRun(a, flowchart, options)
+	if err != nil {
+		log.Printf("StartExecution failed: %v", err)
+		return "", err
+	}
+	a.run = run
+	a.isExecuting = true
+	a.lastFlow = flow
+	log.Printf("Execution %s started", run.ID)
+	run.start()
+	return run.ID, nil
+}
 
-		// Get numberOfClicks
-		numberOfClicks, ok := task.Data["numberOfClicks"].(float64)
-		if !ok {
-			err := fmt.Sprintf("Invalid numberOfClicks value: %v", task.Data["numberOfClicks"])
-			log.Printf("Click error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
+// StopExecution stops the ongoing execution.
+func (a *App) StopExecution() {
+	run := a.currentRun()
+	if run == nil || !a.endRun(run) {
+		log.Println("StopExecution called but no execution is in progress")
+		return
+	}
 
-		// Get clickDelay
-		clickDelay, ok := task.Data["clickDelay"].(float64)
-		if !ok {
-			clickDelay = 0.1 // Default delay of 100ms
-		}
-		clickDuration := time.Duration(clickDelay) * time.Millisecond
+	// Stop outside the lock: workers may still be finishing their current task
+	run.stop()
+	run.emit("execution-stopped", nil)
+	log.Printf("Execution %s has been stopped by the user", run.ID)
+}
 
-		// Get pressReleaseDelay and releaseAfterPress
-		pressReleaseDelay, ok := task.Data["pressReleaseDelay"].(float64)
-		if !ok {
-			pressReleaseDelay = 0.1 // Default press duration of 100ms
-		}
-		pressDuration := time.Duration(pressReleaseDelay) * time.Millisecond
-
-		releaseAfterPress, _ := task.Data["releaseAfterPress"].(bool)
-
-		//TODO: standardise execution in order for all blcoks by how the block is displayed e.g clicks first then scroll
-		// Perform the click actions
-		if numberOfClicks > 0 {
-			log.Printf("Performing %v clicks with %v delay and %v press duration", //TODO: use this kind of scentence to summarise blocks on mininmise and log to console
-				numberOfClicks, clickDuration, pressDuration)
-
-			for i := 0; i < int(numberOfClicks); i++ {
-				if releaseAfterPress {
-					robotgo.MouseDown(buttonType)
-					time.Sleep(pressDuration)
-					robotgo.MouseUp(buttonType)
-				} else {
-					robotgo.Click(buttonType)
-				}
-
-				if i < int(numberOfClicks)-1 {
-					time.Sleep(clickDuration)
-				}
-			}
-		}
+// PauseExecution suspends the current run between nodes. A node that is
+// sleeping (e.g. a DelayNode) is interrupted and resumes with its remaining
+// time.
+func (a *App) PauseExecution() error {
+	run := a.currentRun()
+	if run == nil {
+		return errors.New("no execution in progress")
+	}
+	if !run.pause() {
+		return errors.New("execution is already paused")
+	}
+	return nil
+}
 
-		// Get scroll options
-		scrollDirections, _ := task.Data["scrollDirection"].([]interface{})
-		scrollLines, hasScrollLines := task.Data["scrollLines"].(float64)
-
-		// Handle scrolling if configured
-		if len(scrollDirections) > 0 && hasScrollLines && scrollLines > 0 {
-			for _, dir := range scrollDirections {
-				direction, ok := dir.(string)
-				if !ok {
-					continue
-				}
-
-				scrollAmount := int(scrollLines)
-				log.Printf("Scrolling %s with %v lines", direction, scrollAmount)
-
-				switch direction {
-				case "Vertical":
-					// For vertical scrolling, positive is down, negative is up
-					robotgo.ScrollDir(scrollAmount, "down")
-				case "Horizontal":
-					// For horizontal scrolling, we use the x,y coordinates method
-					// Positive scrollAmount moves right, negative moves left
-					robotgo.Scroll(scrollAmount, 0)
-				}
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
+// ResumeExecution continues a paused run.
+func (a *App) ResumeExecution() error {
+	run := a.currentRun()
+	if run == nil {
+		return errors.New("no execution in progress")
+	}
+	if !run.resume() {
+		return errors.New("execution is not paused")
+	}
+	return nil
+}
 
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "Click",
-		})
-
-	case "TypeString":
-		log.Printf("TypeString task starting - Data: %+v", task.Data)
-		text, ok := task.Data["text"].(string)
-		if !ok {
-			err := fmt.Sprintf("Invalid text value: %v", task.Data["text"])
-			log.Printf("TypeString error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
-		log.Printf("Typing text: %s", text)
-		robotgo.TypeStr(text)
-		time.Sleep(100 * time.Millisecond)
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "TypeString",
-		})
-
-	case "KeyTap":
-		log.Printf("KeyTap task starting - Data: %+v", task.Data)
-		key, ok := task.Data["key"].(string)
-		if !ok {
-			err := fmt.Sprintf("Invalid key value: %v", task.Data["key"])
-			log.Printf("KeyTap error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
-		log.Printf("Tapping key: %s", key)
-		robotgo.KeyTap(key)
-		time.Sleep(100 * time.Millisecond)
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "KeyTap",
-		})
-	case "DelayNode":
-		log.Printf("Delay task starting - Data: %+v", task.Data)
-
-		// Get delayType
-		delayType, ok := task.Data["delayType"].(string)
-		if !ok {
-			err := fmt.Sprintf("Invalid delayType value: %v", task.Data["delayType"])
-			log.Printf("Delay error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
+// StepExecution runs the node a debug run is stopped at, then stops again
+// before the next node.
+func (a *App) StepExecution() error {
+	run := a.currentRun()
+	if run == nil {
+		return errors.New("no execution in progress")
+	}
+	return run.debug.release(true)
+}
 
-		switch delayType {
-		case "Fixed":
-			// Get time
-			timeFloat, ok := task.Data["time"].(float64)
-			if !ok {
-				err := fmt.Sprintf("Invalid time value: %v", task.Data["time"])
-				log.Printf("Delay error: %s for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  err,
-				})
-				return
-			}
-			duration := time.Duration(timeFloat) * time.Millisecond
-			log.Printf("Executing fixed delay of %v milliseconds", timeFloat)
-			time.Sleep(duration)
-
-		case "Random":
-			// Get minTime and maxTime
-			minTimeFloat, ok1 := task.Data["minTime"].(float64)
-			maxTimeFloat, ok2 := task.Data["maxTime"].(float64)
-			if !ok1 || !ok2 {
-				err := fmt.Sprintf("Invalid minTime or maxTime values: minTime=%v, maxTime=%v", task.Data["minTime"], task.Data["maxTime"])
-				log.Printf("Delay error: %s for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  err,
-				})
-				return
-			}
-			if minTimeFloat > maxTimeFloat {
-				err := "minTime cannot be greater than maxTime"
-				log.Printf("Delay error: %s for task %s", err, task.ID)
-				app.emitEvent("task-error", map[string]interface{}{
-					"taskID": task.ID,
-					"error":  err,
-				})
-				return
-			}
+// ContinueExecution runs the node a debug run is stopped at and carries on
+// until the next node flagged with a breakpoint.
+func (a *App) ContinueExecution() error {
+	run := a.currentRun()
+	if run == nil {
+		return errors.New("no execution in progress")
+	}
+	return run.debug.release(false)
+}
 
-			// Generate random delay using a local random generator
-			r := rand.New(rand.NewSource(time.Now().UnixNano()))
-			delay := minTimeFloat + r.Float64()*(maxTimeFloat-minTimeFloat)
-			duration := time.Duration(delay) * time.Millisecond
-			log.Printf("Executing random delay between %v and %v milliseconds. Selected delay: %v milliseconds", minTimeFloat, maxTimeFloat, delay)
-			time.Sleep(duration)
-
-		default:
-			err := fmt.Sprintf("Unsupported delayType: %s", delayType)
-			log.Printf("Delay error: %s for task %s", err, task.ID)
-			app.emitEvent("task-error", map[string]interface{}{
-				"taskID": task.ID,
-				"error":  err,
-			})
-			return
-		}
+// GetIsPaused returns whether the current run is paused.
+func (a *App) GetIsPaused() bool {
+	run := a.currentRun()
+	return run != nil && run.gate.IsPaused()
+}
 
-		app.emitEvent("task-success", map[string]interface{}{
-			"taskID": task.ID,
-			"type":   "Delay",
-		})
+// currentRun returns the run in progress, or nil.
+func (a *App) currentRun() *ExecutionRun {
+	a.execMutex.Lock()
+	defer a.execMutex.Unlock()
+	return a.run
+}
 
-	default:
-		err := fmt.Sprintf("Unknown task type: %s", task.Type)
-		log.Printf("%s for task %s", err, task.ID)
-		app.emitEvent("task-error", map[string]interface{}{
-			"taskID": task.ID,
-			"error":  err,
-		})
+// endRun clears the execution state if run is still the current run and
+// reports whether it did. Finishing, stopping and aborting all go through
+// it, so only the first of them to happen emits how the run ended, and a
+// run that was already stopped can't clear the state of a newer one.
+func (a *App) endRun(run *ExecutionRun) bool {
+	a.execMutex.Lock()
+	defer a.execMutex.Unlock()
+	if a.run != run {
+		return false
 	}
+	a.run = nil
+	a.isExecuting = false
+	log.Printf("Execution state set to: %v", false)
+	return true
 }
 
-// StartExecution receives the flowchart data and starts execution.
-func (a *App) StartExecution(flow string) error {
+//=============================================== Recording ===============================================
+
+// StartRecording begins capturing global mouse and keyboard input until
+// StopRecording is called.
+func (a *App) StartRecording() error {
+	hotkeys := a.GetSettings().Hotkeys.sequences()
 	a.execMutex.Lock()
 	defer a.execMutex.Unlock()
 
+	if a.hooks == nil {
+		return fmt.Errorf("recording is not supported: %w", errHooksUnsupported)
+	}
+	if a.recording != nil {
+		return errors.New("already recording")
+	}
 	if a.isExecuting {
-		log.Println("StartExecution called but execution is already in progress")
-		return errors.New("execution already in progress")
+		return errors.New("cannot record while a flow is running")
 	}
-	a.isExecuting = true
-	log.Println("Execution started")
-	defer func() {
-		if r := recover(); r != nil {
-			a.setExecuting(false)
-			log.Printf("Recovered from panic in StartExecution: %v", r)
-			a.emitEvent("execution-error", fmt.Sprintf("panic: %v", r))
-		}
-	}()
-
-	var flowchart Flowchart
-	err := json.Unmarshal([]byte(flow), &flowchart)
+	rec, err := startRecording(a.hooks, hotkeys)
 	if err != nil {
-		a.isExecuting = false
-		log.Printf("Failed to unmarshal flowchart: %v", err)
-		return fmt.Errorf("invalid flowchart data: %w", err)
+		return fmt.Errorf("failed to start recording: %w", err)
 	}
+	a.recording = rec
+	log.Println("Recording started")
+	a.emitEvent("recording-started", nil)
+	return nil
+}
 
-	// Validate flowchart
-	if len(flowchart.Nodes) == 0 {
-		a.isExecuting = false
-		log.Println("Flowchart validation failed: no nodes found")
-		return errors.New("flowchart must contain at least one node")
-	}
+// StopRecording ends the recording and returns it as a flow of nodes
+// chained from a StartNode, ready to load into the editor.
+func (a *App) StopRecording() (FlowData, error) {
+	a.execMutex.Lock()
+	rec := a.recording
+	a.recording = nil
+	a.execMutex.Unlock()
 
-	// Build node map for easy access
-	a.nodeMap = make(map[string]Node)
-	for _, node := range flowchart.Nodes {
-		a.nodeMap[node.ID] = node
+	if rec == nil {
+		return FlowData{}, errors.New("not recording")
 	}
+	events := rec.stop()
+	flow := buildRecordedFlow(events, rec.hotkeys)
+	log.Printf("Recorded %d events as %d nodes", len(events), len(flow.Nodes))
+	a.emitEvent("recording-stopped", map[string]interface{}{
+		"events": len(events),
+		"nodes":  len(flow.Nodes),
+	})
+	return flow, nil
+}
 
-	// Filter out unconnected nodes
-	connectedNodeIDs := make(map[string]bool)
-	for _, edge := range flowchart.Edges {
-		connectedNodeIDs[edge.Source] = true
-		connectedNodeIDs[edge.Target] = true
-	}
+// GetInputHooksSupported returns whether this platform can capture global
+// input, which recording and hotkeys need.
+func (a *App) GetInputHooksSupported() bool {
+	return a.hooks != nil
+}
 
-	connectedNodeMap := make(map[string]Node)
-	for id := range connectedNodeIDs {
-		if node, exists := a.nodeMap[id]; exists {
-			connectedNodeMap[id] = node
-		}
-	}
-	a.nodeMap = connectedNodeMap
+// GetIsRecording returns whether input is being recorded.
+func (a *App) GetIsRecording() bool {
+	a.execMutex.Lock()
+	defer a.execMutex.Unlock()
+	return a.recording != nil
+}
 
-	// Build dependencies
-	a.dependencies = make(map[string][]string)
-	for _, edge := range flowchart.Edges {
-		a.dependencies[edge.Source] = append(a.dependencies[edge.Source], edge.Target)
-	}
+//=============================================== Settings ===============================================
 
-	// Initialize completed map
-	a.completedMux.Lock()
-	a.completed = make(map[string]bool)
-	a.completedMux.Unlock()
+// GetSettings returns the current application settings.
+func (a *App) GetSettings() Settings {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	return a.settings
+}
 
-	// Enqueue initial tasks (StartNode)
-	startNode, err := a.findStartNode(flowchart.Nodes)
-	if err != nil {
-		a.isExecuting = false
-		log.Printf("StartExecution failed: %v", err)
+// SaveSettings validates, saves and applies settings, re-registering the
+// global hotkeys. Where input hooks aren't supported the hotkeys are saved
+// but stay inactive.
+func (a *App) SaveSettings(settings Settings) error {
+	if err := settings.validate(); err != nil {
 		return err
 	}
-
-	task := Task{
-		ID:   startNode.ID,
-		Type: startNode.Type,
-		Data: startNode.Data,
+	if err := utils.SaveSettings(settings); err != nil {
+		return err
 	}
-	a.taskQueue.Enqueue(task)
-
-	// Start a goroutine to handle task completions
-	go a.handleCompletions()
 
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	a.settings = settings
+	if err := a.applyHotkeys(); !errors.Is(err, errHooksUnsupported) {
+		return err
+	}
 	return nil
 }
 
-// findStartNode locates the StartNode in the flowchart.
-func (a *App) findStartNode(nodes []Node) (Node, error) {
-	for _, node := range nodes {
-		if node.Type == "StartNode" {
-			return node, nil
-		}
+// applyHotkeys (re)starts the hotkey listener with the current settings.
+// The caller must hold settingsMu.
+func (a *App) applyHotkeys() error {
+	if a.hotkeys != nil {
+		a.hotkeys.stop()
+		a.hotkeys = nil
 	}
-	return Node{}, errors.New("no Start node found in flowchart")
-}
-
-// notifyTaskCompletion is called by TaskQueue workers when a task is completed.
-func (a *App) notifyTaskCompletion(taskID string) {
-	select {
-	case a.notifyCh <- taskID:
-		log.Printf("Task %s completion notified", taskID)
-	default:
-		log.Printf("Notification channel full. Unable to notify completion of task %s", taskID)
-	}
-}
-
-// handleCompletions listens for completed tasks and enqueues dependent tasks.
-func (a *App) handleCompletions() {
-	for {
-		select {
-		case taskID := <-a.notifyCh:
-			a.completedMux.Lock()
-			a.completed[taskID] = true
-			a.completedMux.Unlock()
-			log.Printf("Task %s marked as completed", taskID)
-
-			// Find dependent tasks
-			dependents := a.dependencies[taskID]
-			for _, depID := range dependents {
-				if a.canEnqueue(depID) {
-					node, exists := a.nodeMap[depID]
-					if !exists {
-						log.Printf("Node ID %s not found in nodeMap", depID)
-						continue
-					}
-					task := Task{
-						ID:   node.ID,
-						Type: node.Type,
-						Data: node.Data,
-					}
-					a.taskQueue.Enqueue(task)
-				}
-			}
-
-			// Check if all tasks are completed
-			a.completedMux.Lock()
-			allCompleted := len(a.completed) == len(a.nodeMap)
-			a.completedMux.Unlock()
-
-			if allCompleted {
-				a.setExecuting(false)
-				a.emitEvent("execution-completed", nil)
-				log.Println("All tasks completed. Execution finished.")
-				return
-			}
-		case <-a.taskQueue.ctx.Done():
-			a.setExecuting(false)
-			log.Println("Execution stopped due to task queue cancellation")
-			a.emitEvent("execution-stopped", nil)
-			return
-		case <-time.After(10 * time.Minute):
-			// Optional: Timeout to prevent indefinite waiting
-			a.setExecuting(false)
-			a.taskQueue.Stop()
-			log.Println("Execution timed out after 10 minutes")
-			a.emitEvent("execution-timed-out", nil)
-			return
-		}
+	if a.hooks == nil {
+		return errHooksUnsupported
+	}
+	listener, err := startHotkeys(a.hooks, a.settings.Hotkeys, a.handleHotkey)
+	if err != nil {
+		return err
 	}
+	a.hotkeys = listener
+	log.Printf("Global hotkeys: %v", a.settings.Hotkeys.bindings())
+	return nil
 }
 
-// canEnqueue checks if all dependencies of a node are met.
-func (a *App) canEnqueue(nodeID string) bool {
-	// Find all nodes that this node depends on
-	var dependencies []string
-	for source, targets := range a.dependencies {
-		for _, target := range targets {
-			if target == nodeID {
-				dependencies = append(dependencies, source)
+// handleHotkey performs a hotkey's action.
+func (a *App) handleHotkey(action string) {
+	log.Printf("Hotkey pressed: %s", action)
+	a.emitEvent("hotkey-pressed", map[string]interface{}{"action": action})
+
+	var err error
+	switch action {
+	case hotkeyStart:
+		a.execMutex.Lock()
+		flow := a.lastFlow
+		a.execMutex.Unlock()
+		if flow == "" {
+			flow, err = a.lastSavedFlow()
+			if err != nil {
+				break
 			}
 		}
-	}
-
-	// Check if all dependencies are completed
-	for _, dep := range dependencies {
-		a.completedMux.Lock()
-		if !a.completed[dep] {
-			a.completedMux.Unlock()
-			return false
+		_, err = a.StartExecution(flow)
+	case hotkeyStop:
+		a.StopExecution()
+	case hotkeyPause:
+		if a.GetIsPaused() {
+			err = a.ResumeExecution()
+		} else {
+			err = a.PauseExecution()
 		}
-		a.completedMux.Unlock()
 	}
-	return true
-}
-
-// StopExecution stops the ongoing execution.
-func (a *App) StopExecution() {
-	a.execMutex.Lock()
-	defer a.execMutex.Unlock()
-
-	if !a.isExecuting {
-		log.Println("StopExecution called but no execution is in progress")
-		return
+	if err != nil {
+		log.Printf("Hotkey %s: %v", action, err)
 	}
-
-	a.taskQueue.Stop()
-	a.setExecuting(false)
-	a.emitEvent("execution-stopped", nil)
-	log.Println("Execution has been stopped by the user")
 }
 
-// setExecuting updates the execution state.
-func (a *App) setExecuting(state bool) {
-	a.execMutex.Lock()
-	a.isExecuting = state
-	a.execMutex.Unlock()
-	log.Printf("Execution state set to: %v", state)
+// lastSavedFlow returns the last saved flow as flowchart JSON.
+func (a *App) lastSavedFlow() (string, error) {
+	flowData, err := a.LoadLastFile()
+	if err != nil {
+		return "", err
+	}
+	if flowData == nil {
+		return "", errors.New("no flow has been run or saved yet")
+	}
+	data, err := json.Marshal(flowData)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
 // emitEvent emits an event to the frontend.
 func (a *App) emitEvent(event string, payload interface{}) {
+	if a.ctx == nil {
+		// Headless (no Wails runtime), so there is no frontend to notify
+		log.Printf("Event %s: %v", event, payload)
+		if a.onEvent != nil {
+			a.onEvent(event, payload)
+		}
+		return
+	}
 	runtime.EventsEmit(a.ctx, event, payload)
 }
 
@@ -0,0 +1,167 @@
+// branch.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func init() {
+	RegisterExecutor("IfNode", ifExecutor{})
+	RegisterExecutor("SwitchNode", switchExecutor{})
+}
+
+// Branch node output handles. SwitchNode cases name their own handles and
+// fall back to the default handle.
+const (
+	HandleTrue    = "true"
+	HandleFalse   = "false"
+	HandleDefault = "default"
+)
+
+// IfData is the data of an IfNode.
+type IfData struct {
+	Condition Condition `json:"condition"`
+}
+
+func decodeIfData(data map[string]interface{}) (IfData, error) {
+	r := newDataReader(data)
+	var d IfData
+	if r.Require("condition", "object") {
+		d.Condition = readCondition(r.Object("condition"))
+	}
+	return d, r.Err()
+}
+
+// SwitchCase is one labelled output of a SwitchNode.
+type SwitchCase struct {
+	Handle    string    `json:"handle"`
+	Condition Condition `json:"condition"`
+}
+
+// SwitchData is the data of a SwitchNode. Cases are tested in order and the
+// first that holds wins.
+type SwitchData struct {
+	Cases []SwitchCase `json:"cases"`
+}
+
+func decodeSwitchData(data map[string]interface{}) (SwitchData, error) {
+	r := newDataReader(data)
+	var d SwitchData
+	seen := map[string]bool{HandleDefault: true, HandleError: true}
+	for i, cr := range r.Objects("cases") {
+		c := SwitchCase{
+			Handle:    cr.String("handle", fmt.Sprintf("case-%d", i)),
+			Condition: readCondition(cr.Object("condition")),
+		}
+		if seen[c.Handle] {
+			cr.Fail("handle", "unique handle", c.Handle, fmt.Sprintf("handle %q is already used", c.Handle))
+		}
+		seen[c.Handle] = true
+		d.Cases = append(d.Cases, c)
+	}
+	return d, r.Err()
+}
+
+//=============================================== If ===============================================
+
+// ifExecutor follows the true handle when its condition holds and the false
+// handle otherwise.
+type ifExecutor struct{}
+
+func (ifExecutor) Validate(node Node) error {
+	_, err := decodeIfData(node.Data)
+	return err
+}
+
+func (ifExecutor) Handles(node Node) []string {
+	return []string{HandleTrue, HandleFalse}
+}
+
+func (ifExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	return decodeIfData(ec.Task.Data)
+}
+
+func (ifExecutor) Describe(node Node) string {
+	d, err := decodeIfData(node.Data)
+	if err != nil {
+		return "Branch on a condition"
+	}
+	return fmt.Sprintf("If %s", d.Condition)
+}
+
+func (ifExecutor) Execute(ec *ExecContext) error {
+	d, err := decodeIfData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	met, err := d.Condition.Evaluate(ec)
+	if err != nil {
+		return err
+	}
+	handle := HandleFalse
+	if met {
+		handle = HandleTrue
+	}
+	followBranch(ec, handle)
+	return nil
+}
+
+//=============================================== Switch ===============================================
+
+// switchExecutor follows the handle of the first case whose condition holds,
+// or the default handle if none does.
+type switchExecutor struct{}
+
+func (switchExecutor) Validate(node Node) error {
+	_, err := decodeSwitchData(node.Data)
+	return err
+}
+
+func (switchExecutor) Handles(node Node) []string {
+	d, _ := decodeSwitchData(node.Data)
+	handles := make([]string, 0, len(d.Cases)+1)
+	for _, c := range d.Cases {
+		handles = append(handles, c.Handle)
+	}
+	return append(handles, HandleDefault)
+}
+
+func (switchExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	return decodeSwitchData(ec.Task.Data)
+}
+
+func (switchExecutor) Describe(node Node) string {
+	d, err := decodeSwitchData(node.Data)
+	if err != nil {
+		return "Branch on several conditions"
+	}
+	return fmt.Sprintf("Branch on %d case(s)", len(d.Cases))
+}
+
+func (switchExecutor) Execute(ec *ExecContext) error {
+	d, err := decodeSwitchData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	for _, c := range d.Cases {
+		met, err := c.Condition.Evaluate(ec)
+		if err != nil {
+			return fmt.Errorf("case %s: %w", c.Handle, err)
+		}
+		if met {
+			followBranch(ec, c.Handle)
+			return nil
+		}
+	}
+	followBranch(ec, HandleDefault)
+	return nil
+}
+
+// followBranch selects handle and reports the decision.
+func followBranch(ec *ExecContext, handle string) {
+	log.Printf("Branch %s taking %s", ec.Task.ID, handle)
+	ec.Emit("branch-taken", map[string]interface{}{"handle": handle})
+	ec.Follow(handle)
+}
@@ -0,0 +1,69 @@
+// branch_test.go
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// branchFlow follows branch to two nodes on each of handles, then joins the
+// branches in one node.
+func branchFlow(branch testNode, handles ...string) string {
+	nodes := []testNode{branch, node("join", "KeyTap", `{"key":"j"}`)}
+	edges := []string{"start->" + branch.id}
+	for _, h := range handles {
+		nodes = append(nodes,
+			node(h, "TypeString", `{"text":"`+h+`"}`),
+			node(h+"-2", "TypeString", `{"text":"`+h+` again"}`))
+		edges = append(edges, branch.id+":"+h+"->"+h, h+"->"+h+"-2", h+"-2->join")
+	}
+	return graph(nodes, edges...)
+}
+
+// runBranch runs flow with the pixel at 1, 1 set to color and checks that
+// only the nodes of handle ran, followed by the join, and that the run
+// completed.
+func runBranch(t *testing.T, flow, color, handle string) {
+	t.Helper()
+	driver := NewRecordingDriver()
+	driver.SetPixel(1, 1, color)
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, flow)
+
+	assertActions(t, driver.ActionStrings(), "type("+handle+")", "type("+handle+" again)", "keyTap(j)")
+	var started []string
+	for _, e := range events.named("task-started") {
+		started = append(started, e.payload["taskID"].(string))
+	}
+	for _, id := range started {
+		if id != "start" && !slices.Contains([]string{"if", "switch", handle, handle + "-2", "join"}, id) {
+			t.Errorf("skipped node %s started", id)
+		}
+	}
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
+
+func TestIfNode(t *testing.T) {
+	flow := branchFlow(node("if", "IfNode",
+		`{"condition":{"type":"pixel","x":1,"y":1,"color":"ff0000","tolerance":8}}`), "true", "false")
+	t.Run("true", func(t *testing.T) { runBranch(t, flow, "f80000", "true") })
+	t.Run("false", func(t *testing.T) { runBranch(t, flow, "00ff00", "false") })
+}
+
+func TestSwitchNode(t *testing.T) {
+	flow := branchFlow(node("switch", "SwitchNode", `{"cases":[
+		{"handle":"red","condition":{"type":"pixel","x":1,"y":1,"color":"ff0000"}},
+		{"handle":"any","condition":{"type":"pixel","x":1,"y":1,"color":"80ff80","tolerance":128}},
+		{"condition":{"type":"pixel","x":1,"y":1,"color":"00ff00"}}
+	]}`), "red", "any", "case-2", "default")
+	t.Run("first case", func(t *testing.T) { runBranch(t, flow, "ff0000", "red") })
+	t.Run("first of several that hold", func(t *testing.T) { runBranch(t, flow, "00ff00", "any") })
+	t.Run("default", func(t *testing.T) {
+		// Too far from green for any case
+		runBranch(t, flow, "ff00ff", "default")
+	})
+}
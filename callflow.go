@@ -0,0 +1,203 @@
+// callflow.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"Keypress/utils"
+)
+
+func init() {
+	RegisterExecutor("CallFlowNode", callFlowExecutor{})
+}
+
+// maxCallDepth is how deeply CallFlowNodes may nest, so a flow that calls
+// itself fails instead of running forever.
+const maxCallDepth = 10
+
+//=============================================== Flow Loading ===============================================
+
+// FlowLoader loads saved flows by name for CallFlowNodes.
+type FlowLoader interface {
+	LoadFlow(name string) (FlowData, error)
+}
+
+// savedFlows loads flows saved in the app data directory.
+type savedFlows struct{}
+
+func (savedFlows) LoadFlow(name string) (FlowData, error) {
+	data, err := utils.LoadFlowData(name)
+	if err != nil {
+		return FlowData{}, err
+	}
+	var flow FlowData
+	if err := json.Unmarshal(data, &flow); err != nil {
+		return FlowData{}, fmt.Errorf("failed to parse flow data: %w", err)
+	}
+	return flow, nil
+}
+
+// MemoryFlows is a FlowLoader over flows held in memory, for headless tests.
+type MemoryFlows struct {
+	mu    sync.Mutex
+	flows map[string]FlowData
+}
+
+// NewMemoryFlows returns an empty MemoryFlows.
+func NewMemoryFlows() *MemoryFlows {
+	return &MemoryFlows{flows: make(map[string]FlowData)}
+}
+
+// Set stores flow under name.
+func (m *MemoryFlows) Set(name string, flow FlowData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flows[name] = flow
+}
+
+func (m *MemoryFlows) LoadFlow(name string) (FlowData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	flow, ok := m.flows[name]
+	if !ok {
+		return FlowData{}, fmt.Errorf("no flow named %q", name)
+	}
+	return flow, nil
+}
+
+//=============================================== Call Flow ===============================================
+
+// CallFlowData is the data of a CallFlowNode.
+type CallFlowData struct {
+	Flow string `json:"flow"` // name of a saved flow, without .json
+	// Inputs are the variables the called flow starts with. It sees no other
+	// variables of the caller.
+	Inputs map[string]interface{} `json:"inputs"`
+	// Outputs maps caller variables to the called flow's variables they are
+	// set from when it ends.
+	Outputs map[string]string `json:"outputs"`
+}
+
+func decodeCallFlowData(data map[string]interface{}) (CallFlowData, error) {
+	r := newDataReader(data).AllowTemplates()
+	d := CallFlowData{
+		Flow:    r.String("flow", ""),
+		Inputs:  r.Object("inputs").data,
+		Outputs: make(map[string]string),
+	}
+	switch {
+	case d.Flow == "":
+		r.Fail("flow", "flow name", d.Flow, "")
+	case !hasTemplate(d.Flow) && (filepath.Base(d.Flow) != d.Flow || d.Flow == ".."):
+		r.Fail("flow", "flow name", d.Flow, "must not contain a path")
+	}
+	outputs := r.Object("outputs")
+	for name := range outputs.data {
+		d.Outputs[name] = outputs.String(name, "")
+		if d.Outputs[name] == "" {
+			outputs.Fail(name, "variable name", d.Outputs[name], "")
+		}
+	}
+	return d, r.Err()
+}
+
+// callFlowExecutor runs a saved flow inline, as a sub-run of the current run.
+type callFlowExecutor struct{}
+
+func (callFlowExecutor) Validate(node Node) error {
+	_, err := decodeCallFlowData(node.Data)
+	return err
+}
+
+func (callFlowExecutor) Assigns(node Node) []string {
+	d, _ := decodeCallFlowData(node.Data)
+	return mapKeys(d.Outputs)
+}
+
+func (callFlowExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCallFlowData(data)
+}
+
+func (callFlowExecutor) Describe(node Node) string {
+	d, err := decodeCallFlowData(node.Data)
+	if err != nil {
+		return "Call a saved flow"
+	}
+	return fmt.Sprintf("Call flow %q", d.Flow)
+}
+
+func (callFlowExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeCallFlowData(data)
+	if err != nil {
+		return err
+	}
+	if len(ec.run.path) >= maxCallDepth {
+		return fmt.Errorf("flow %q: calls nested more than %d deep", d.Flow, maxCallDepth)
+	}
+
+	flow, err := ec.run.app.flows.LoadFlow(d.Flow)
+	if err != nil {
+		return fmt.Errorf("flow %q: %w", d.Flow, err)
+	}
+	if err := validateFlow(flow).Err(); err != nil {
+		return fmt.Errorf("flow %q: %w", d.Flow, err)
+	}
+
+	vars := newVariableStore()
+	for name, value := range d.Inputs {
+		vars.Set(name, value)
+	}
+	sub, err := ec.run.newSubRun(ec.Context(), ec.Task.ID, flow, vars)
+	if err != nil {
+		return fmt.Errorf("flow %q: %w", d.Flow, err)
+	}
+	log.Printf("Calling flow %q from %s", d.Flow, ec.Task.ID)
+	ec.Emit("flow-called", map[string]interface{}{"flow": d.Flow})
+
+	sub.start()
+	select {
+	case <-sub.ended:
+	case <-ec.Context().Done():
+	}
+	sub.stop()
+	if err := ec.Context().Err(); err != nil {
+		return err
+	}
+	if sub.outcome != nil {
+		return fmt.Errorf("flow %q: %w", d.Flow, sub.outcome)
+	}
+
+	// Sort so a missing output is reported the same way every time
+	names := make([]string, 0, len(d.Outputs))
+	for name := range d.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	outputs := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		value, ok := vars.Get(d.Outputs[name])
+		if !ok {
+			return fmt.Errorf("flow %q: output %s: variable %q was not set", d.Flow, name, d.Outputs[name])
+		}
+		outputs[name] = value
+	}
+	for _, name := range names {
+		ec.SetVariable(name, outputs[name])
+	}
+	ec.Emit("flow-returned", map[string]interface{}{"flow": d.Flow, "outputs": outputs})
+	return nil
+}
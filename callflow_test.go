@@ -0,0 +1,117 @@
+// callflow_test.go
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// callFlow is a CallFlowNode that calls the flow called name.
+func callFlow(id, name, inputs, outputs string) testNode {
+	return node(id, "CallFlowNode", `{"flow":"`+name+`","inputs":`+inputs+`,"outputs":`+outputs+`}`)
+}
+
+// newCallingApp returns an App over a RecordingDriver that loads flows from
+// a MemoryFlows.
+func newCallingApp(t *testing.T, flows map[string]string) (*App, *RecordingDriver, *eventLog) {
+	t.Helper()
+	memory := NewMemoryFlows()
+	for name, flow := range flows {
+		memory.Set(name, parseFlow(t, flow))
+	}
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	app.flows = memory
+	return app, driver, recordEvents(app)
+}
+
+func TestCallFlow(t *testing.T) {
+	app, driver, events := newCallingApp(t, map[string]string{
+		"greet": chain(
+			node("hello", "TypeString", `{"text":"hello {{name}}"}`),
+			node("set", "SetVariableNode", `{"name":"reply","value":"hi {{name}}"}`),
+		),
+	})
+	runFlowOn(t, app, chain(
+		node("before", "SetVariableNode", `{"name":"name","value":"caller"}`),
+		callFlow("call", "greet", `{"name":"bob"}`, `{"answer":"reply"}`),
+		node("after", "TypeString", `{"text":"{{answer}} from {{name}}"}`),
+	))
+
+	assertActions(t, driver.ActionStrings(), "type(hello bob)", "type(hi bob from caller)")
+	returned := events.named("flow-returned")
+	if len(returned) != 1 || returned[0].payload["flow"] != "greet" {
+		t.Fatalf("flow-returned events: %+v", returned)
+	}
+	if outputs, _ := returned[0].payload["outputs"].(map[string]interface{}); outputs["answer"] != "hi bob" {
+		t.Errorf("outputs %v", outputs)
+	}
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
+
+func TestCallFlowMissingOutput(t *testing.T) {
+	app, driver, events := newCallingApp(t, map[string]string{
+		"empty": chain(node("type", "TypeString", `{"text":"x"}`)),
+	})
+	runFlowOn(t, app, chain(
+		callFlow("call", "empty", `{}`, `{"answer":"reply"}`),
+		node("after", "TypeString", `{"text":"y"}`),
+	))
+
+	assertActions(t, driver.ActionStrings(), "type(x)")
+	failed := events.wait("execution-failed")
+	if len(failed) != 1 || failed[0].payload["taskID"] != "call" ||
+		!strings.Contains(failed[0].payload["error"].(string), `variable "reply" was not set`) {
+		t.Errorf("execution-failed events: %+v", failed)
+	}
+}
+
+func TestCallFlowPath(t *testing.T) {
+	app, _, events := newCallingApp(t, map[string]string{
+		"outer": chain(callFlow("inner-call", "inner", `{}`, `{}`)),
+		"inner": chain(node("type", "TypeString", `{"text":"x"}`)),
+	})
+	runFlowOn(t, app, chain(callFlow("outer-call", "outer", `{}`, `{}`)))
+
+	paths := make(map[string][]string)
+	for _, e := range events.named("task-started") {
+		path, _ := e.payload["path"].([]string)
+		paths[e.payload["taskID"].(string)] = path
+	}
+	want := map[string][]string{
+		"outer-call": nil,
+		"inner-call": {"outer-call"},
+		"type":       {"outer-call", "inner-call"},
+	}
+	for id, path := range want {
+		if got, ok := paths[id]; !ok || !slices.Equal(got, path) {
+			t.Errorf("%s: path %q, want %q", id, got, path)
+		}
+	}
+	for _, e := range events.named("flow-called") {
+		if e.payload["flow"] == "inner" && !slices.Equal(e.payload["path"].([]string), []string{"outer-call"}) {
+			t.Errorf("flow-called from inner-call has path %v", e.payload["path"])
+		}
+	}
+}
+
+func TestCallFlowRecursion(t *testing.T) {
+	app, driver, events := newCallingApp(t, map[string]string{
+		"self": chain(node("type", "TypeString", `{"text":"x"}`), callFlow("call", "self", `{}`, `{}`)),
+	})
+	runFlowOn(t, app, chain(callFlow("call", "self", `{}`, `{}`)))
+
+	// Each of maxCallDepth nested runs types once before the next call fails
+	if n := len(driver.ActionStrings()); n != maxCallDepth {
+		t.Errorf("%d actions, want %d", n, maxCallDepth)
+	}
+	failed := events.wait("execution-failed")
+	if len(failed) != 1 || failed[0].payload["taskID"] != "call" ||
+		!strings.Contains(failed[0].payload["error"].(string), "calls nested more than 10 deep") {
+		t.Errorf("execution-failed events: %+v", failed)
+	}
+}
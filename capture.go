@@ -0,0 +1,89 @@
+// capture.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// Rect is a screen region. The zero Rect means the whole screen.
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Empty reports whether r covers no area, i.e. stands for the whole screen.
+func (r Rect) Empty() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+// ScreenCapturer grabs screen images for nodes that look at the screen.
+// Drivers that can capture implement it alongside InputDriver.
+type ScreenCapturer interface {
+	// Capture returns the pixels of region, or of the whole screen if region
+	// is empty. The image bounds start at region's top-left corner.
+	Capture(region Rect) (image.Image, error)
+}
+
+func (robotgoDriver) Capture(region Rect) (image.Image, error) {
+	if region.Empty() {
+		return robotgo.CaptureImg()
+	}
+	img, err := robotgo.CaptureImg(region.X, region.Y, region.Width, region.Height)
+	if err != nil {
+		return nil, err
+	}
+	// robotgo's image starts at 0, 0; shift it to screen coordinates
+	return offsetImage{img, image.Pt(region.X, region.Y)}, nil
+}
+
+// offsetImage moves an image's origin.
+type offsetImage struct {
+	image.Image
+	offset image.Point
+}
+
+func (o offsetImage) Bounds() image.Rectangle {
+	return o.Image.Bounds().Add(o.offset)
+}
+
+func (o offsetImage) At(x, y int) color.Color {
+	return o.Image.At(x-o.offset.X, y-o.offset.Y)
+}
+
+// SetScreen sets the image Capture serves, standing in for the whole screen.
+func (d *RecordingDriver) SetScreen(img image.Image) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.screen = img
+}
+
+func (d *RecordingDriver) Capture(region Rect) (image.Image, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("capture", region.X, region.Y, region.Width, region.Height)
+	if d.screen == nil {
+		return nil, errors.New("no screen image set")
+	}
+	if region.Empty() {
+		return d.screen, nil
+	}
+	bounds := image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
+	if !bounds.In(d.screen.Bounds()) {
+		return nil, fmt.Errorf("region %v is outside the screen %v", bounds, d.screen.Bounds())
+	}
+	sub, ok := d.screen.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("screen image %T can't be cropped", d.screen)
+	}
+	return sub.SubImage(bounds), nil
+}
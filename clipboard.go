@@ -0,0 +1,112 @@
+// clipboard.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// Clipboard reads and writes the system clipboard as text. Drivers that can
+// reach it implement it alongside InputDriver.
+type Clipboard interface {
+	ReadClipboard() (string, error)
+	WriteClipboard(text string) error
+}
+
+func (robotgoDriver) ReadClipboard() (string, error) {
+	return robotgo.ReadAll()
+}
+
+func (robotgoDriver) WriteClipboard(text string) error {
+	return robotgo.WriteAll(text)
+}
+
+// SetClipboard sets the clipboard contents without recording an action.
+func (d *RecordingDriver) SetClipboard(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clipboard = text
+}
+
+// SetClipboardError makes ReadClipboard fail with err, as it does on some
+// platforms when the clipboard is empty or holds no text; nil clears it.
+func (d *RecordingDriver) SetClipboardError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clipboardErr = err
+}
+
+func (d *RecordingDriver) ReadClipboard() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.clipboardErr != nil {
+		return "", d.clipboardErr
+	}
+	return d.clipboard, nil
+}
+
+func (d *RecordingDriver) WriteClipboard(text string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clipboard = text
+	d.record("clipboard", text)
+	return nil
+}
+
+// defaultPasteShortcut is the paste shortcut of the platform.
+func defaultPasteShortcut() string {
+	if runtime.GOOS == "darwin" {
+		return "cmd+v"
+	}
+	return "ctrl+v"
+}
+
+// pasteSteps types steps by pasting their text instead of typing it. After
+// each paste it waits PasteDelay, so the target application has read the
+// clipboard before it changes again. The text that was on the clipboard is put
+// back afterwards, even if the run is stopped. If there is no text to read,
+// as with an empty clipboard or an image on it, nothing is put back.
+func pasteSteps(ec *ExecContext, steps []keyStep, d TypeStringData) (err error) {
+	if ec.Clipboard == nil {
+		return errors.New("the clipboard is not available with this input driver")
+	}
+	// Parallel branches pasting at once would overwrite each other's text
+	ec.run.app.clipboardMu.Lock()
+	defer ec.run.app.clipboardMu.Unlock()
+
+	if saved, readErr := ec.Clipboard.ReadClipboard(); readErr != nil {
+		log.Printf("Not restoring the clipboard after pasting, as it couldn't be read: %v", readErr)
+	} else {
+		defer func() {
+			if restoreErr := ec.Clipboard.WriteClipboard(saved); restoreErr != nil && err == nil {
+				err = fmt.Errorf("restoring the clipboard: %w", restoreErr)
+			}
+		}()
+	}
+
+	for _, step := range steps {
+		if step.Text != "" {
+			if err := ec.Clipboard.WriteClipboard(step.Text); err != nil {
+				return fmt.Errorf("writing the clipboard: %w", err)
+			}
+			if err := tapChords(ec, d.PasteShortcut); err != nil {
+				return err
+			}
+			if err := ec.Sleep(time.Duration(d.PasteDelay * float64(time.Millisecond))); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < step.Repeat; i++ {
+			if err := tapChords(ec, step.Chords); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
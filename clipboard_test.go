@@ -0,0 +1,60 @@
+// clipboard_test.go
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPasteRestoresClipboard(t *testing.T) {
+	driver := NewRecordingDriver()
+	driver.SetClipboard("original")
+	runFlowOn(t, NewAppWithDriver(driver), chain(
+		node("a", "TypeString", `{"text":"héllo ✓","mode":"paste","pasteShortcut":"ctrl+v","pasteDelay":10}`),
+		node("b", "TypeString", `{"text":"a{Tab}b","mode":"paste","keys":true,"pasteShortcut":"shift+insert","pasteDelay":10}`),
+	))
+	assertActions(t, driver.ActionStrings(),
+		"clipboard(héllo ✓)", "keyTap(ctrl+v)", "clipboard(original)",
+		"clipboard(a)", "keyTap(shift+insert)", "keyTap(tab)",
+		"clipboard(b)", "keyTap(shift+insert)", "clipboard(original)",
+	)
+}
+
+func TestPasteRestoresClipboardWhenStopped(t *testing.T) {
+	driver := NewRecordingDriver()
+	driver.SetClipboard("original")
+	app := NewAppWithDriver(driver)
+	flow := chain(node("a", "TypeString", `{"text":"x","mode":"paste","pasteShortcut":"ctrl+v","pasteDelay":5000}`))
+	if _, err := app.StartExecution(flow); err != nil {
+		t.Fatal(err)
+	}
+	// Stop while it waits for the application to read the clipboard
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(driver.ActionStrings(), "keyTap(ctrl+v)") {
+		if time.Now().After(deadline) {
+			t.Fatal("the text was not pasted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	app.StopExecution()
+	waitIdle(t, app)
+
+	assertActions(t, driver.ActionStrings(), "clipboard(x)", "keyTap(ctrl+v)", "clipboard(original)")
+}
+
+func TestPasteWithUnreadableClipboard(t *testing.T) {
+	driver := NewRecordingDriver()
+	driver.SetClipboardError(errors.New("no text on the clipboard"))
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, chain(node("a", "TypeString", `{"text":"x","mode":"paste","pasteShortcut":"ctrl+v","pasteDelay":10}`)))
+
+	// It pastes, and has nothing to put back
+	assertActions(t, driver.ActionStrings(), "clipboard(x)", "keyTap(ctrl+v)")
+	if errs := events.named("task-error"); len(errs) > 0 {
+		t.Errorf("the paste failed: %v", errs)
+	}
+}
@@ -0,0 +1,262 @@
+// conditions.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Condition is a test evaluated while a flow runs, e.g. by an IfNode or to
+// decide whether a LoopNode goes round again. Which fields apply depends on
+// Type.
+type Condition struct {
+	Type string `json:"type"` // "variable", "pixel", "key", "result" or "window"
+
+	// variable: compare a run variable with Value
+	Variable string      `json:"variable,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+
+	// pixel: the screen colour at X, Y is within Tolerance of Color
+	X         int    `json:"x,omitempty"`
+	Y         int    `json:"y,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Tolerance int    `json:"tolerance,omitempty"` // per channel, 0-255
+
+	// key: Key is held down ("down") or not ("up"), by the flow or, where
+	// the driver can read the keyboard, by the user. Key may be a {{name}}
+	// template, expanded and checked when the condition is tested.
+	Key   string `json:"key,omitempty"`
+	State string `json:"state,omitempty"`
+
+	// result: Node, or the node that ran just before, ended with Status
+	Node   string `json:"node,omitempty"`
+	Status string `json:"status,omitempty"` // "success" or "error"
+
+	// window: the active window matches Title and/or Process
+	WindowMatch
+}
+
+var conditionOperators = []string{"==", "!=", "<", "<=", ">", ">=", "contains"}
+
+// readCondition decodes a condition object.
+func readCondition(r *dataReader) Condition {
+	c := Condition{Type: r.OneOf("type", "variable", "variable", "pixel", "key", "result", "window")}
+	switch c.Type {
+	case "pixel":
+		r.Require("color", "hex colour")
+		c.X = r.Int("x", 0)
+		c.Y = r.Int("y", 0)
+		c.Color = r.String("color", "")
+		c.Tolerance = r.Int("tolerance", 0)
+		if _, err := parseHexColor(c.Color); r.Has("color") && err != nil {
+			r.Fail("color", "hex colour", c.Color, "")
+		}
+		r.Min("tolerance", float64(c.Tolerance), 0)
+	case "key":
+		c.Key = r.String("key", "")
+		c.State = r.OneOf("state", "down", "down", "up")
+		switch key, err := parseKey(c.Key); {
+		case c.Key == "":
+			r.Fail("key", "non-empty string", c.Key, "")
+		case hasTemplate(c.Key):
+		case err != nil:
+			r.Fail("key", "key name", c.Key, err.Error())
+		default:
+			c.Key = key
+		}
+	case "result":
+		c.Node = r.String("node", "")
+		c.Status = r.OneOf("status", "success", "success", "error")
+	case "window":
+		c.WindowMatch = readWindowMatch(r)
+	default:
+		c.Variable = r.String("variable", "")
+		c.Operator = r.OneOf("operator", "==", conditionOperators...)
+		c.Value = r.Value("value")
+		if c.Variable == "" {
+			r.Fail("variable", "non-empty string", c.Variable, "")
+		}
+	}
+	return c
+}
+
+// String renders the condition for descriptions and logs.
+func (c Condition) String() string {
+	switch c.Type {
+	case "pixel":
+		return fmt.Sprintf("pixel %d, %d is #%s", c.X, c.Y, strings.TrimPrefix(c.Color, "#"))
+	case "key":
+		return fmt.Sprintf("%s is %s", c.Key, c.State)
+	case "result":
+		if c.Node == "" {
+			return fmt.Sprintf("previous node ended with %s", c.Status)
+		}
+		return fmt.Sprintf("node %s ended with %s", c.Node, c.Status)
+	case "window":
+		return fmt.Sprintf("active window has %s", c.WindowMatch)
+	default:
+		return fmt.Sprintf("%s %s %v", c.Variable, c.Operator, c.Value)
+	}
+}
+
+// Evaluate tests the condition against the run's current state.
+func (c Condition) Evaluate(ec *ExecContext) (bool, error) {
+	switch c.Type {
+	case "variable":
+		v, ok := ec.Variable(c.Variable)
+		if !ok {
+			return false, fmt.Errorf("variable %q is not set", c.Variable)
+		}
+		return compareValues(v, c.Operator, c.Value)
+
+	case "pixel":
+		want, err := parseHexColor(c.Color)
+		if err != nil {
+			return false, err
+		}
+		got, err := parseHexColor(ec.Driver.PixelColor(c.X, c.Y))
+		if err != nil {
+			return false, fmt.Errorf("reading pixel %d, %d: %w", c.X, c.Y, err)
+		}
+		return colorDistance(want, got) <= c.Tolerance, nil
+
+	case "key":
+		key := c.Key
+		if hasTemplate(key) {
+			expanded, err := expandTemplate(key, ec.lookupTemplate)
+			if err != nil {
+				return false, err
+			}
+			if key, err = parseKey(expanded); err != nil {
+				return false, err
+			}
+		}
+		down := ec.run.held.IsKeyHeld(key)
+		if !down && ec.KeyState != nil {
+			var err error
+			if down, err = ec.KeyState.IsKeyDown(key); err != nil {
+				return false, err
+			}
+		}
+		return down == (c.State == "down"), nil
+
+	case "result":
+		nodeID := c.Node
+		if nodeID == "" {
+			prev, ok := ec.run.previousNode(ec.Task.ID)
+			if !ok {
+				return false, fmt.Errorf("no node has run before %s", ec.Task.ID)
+			}
+			nodeID = prev
+		}
+		result, ok := ec.run.result(nodeID)
+		if !ok {
+			return false, fmt.Errorf("node %s has not run yet", nodeID)
+		}
+		return result.Status == c.Status, nil
+
+	case "window":
+		w, err := activeWindow(ec)
+		if err != nil {
+			return false, err
+		}
+		return c.Matches(w)
+
+	default:
+		return false, fmt.Errorf("unknown condition type %q", c.Type)
+	}
+}
+
+// compareValues applies op to left and right. Values that both look like
+// numbers, including numeric strings, compare numerically; anything else
+// compares as text.
+func compareValues(left interface{}, op string, right interface{}) (bool, error) {
+	if op == "contains" {
+		return strings.Contains(fmt.Sprint(left), fmt.Sprint(right)), nil
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if lok && rok {
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		}
+		return false, fmt.Errorf("unknown operator %q", op)
+	}
+
+	ls, rs := fmt.Sprint(left), fmt.Sprint(right)
+	switch op {
+	case "==":
+		return ls == rs, nil
+	case "!=":
+		return ls != rs, nil
+	case "<", "<=", ">", ">=":
+		return false, fmt.Errorf("cannot compare %q %s %q: both values must be numbers", ls, op, rs)
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// toNumber converts JSON numbers and numeric strings to float64.
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+//=============================================== Colours ===============================================
+
+// rgb is a colour with 8-bit channels.
+type rgb [3]int
+
+// parseHexColor parses "#rrggbb" or "rrggbb".
+func parseHexColor(s string) (rgb, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 {
+		return rgb{}, fmt.Errorf("invalid colour %q: expected 6 hex digits", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rgb{}, fmt.Errorf("invalid colour %q: %w", s, err)
+	}
+	return rgb{int(n >> 16 & 0xff), int(n >> 8 & 0xff), int(n & 0xff)}, nil
+}
+
+// colorDistance is the largest difference between any channel of a and b.
+func colorDistance(a, b rgb) int {
+	worst := 0
+	for i := range a {
+		d := a[i] - b[i]
+		if d < 0 {
+			d = -d
+		}
+		if d > worst {
+			worst = d
+		}
+	}
+	return worst
+}
@@ -0,0 +1,51 @@
+// conditions_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplatedKeyCondition(t *testing.T) {
+	flow := func(key string) string {
+		return graph([]testNode{
+			node("set", "SetVariableNode", `{"name":"k","value":"`+key+`"}`),
+			node("hold", "KeyPressNode", `{"key":"shift","action":"Hold"}`),
+			node("if", "IfNode", `{"condition":{"type":"key","key":"{{k}}","state":"down"}}`),
+			node("yes", "KeyTap", `{"key":"y"}`),
+			node("no", "KeyTap", `{"key":"n"}`),
+		}, "start->set", "set->hold", "hold->if", "if:true->yes", "if:false->no")
+	}
+
+	for key, want := range map[string]string{"Shift": "keyTap(y)", "ctrl": "keyTap(n)"} {
+		got := withoutOp(withoutOp(runFlow(t, flow(key)), "keyDown"), "keyUp")
+		assertActions(t, got, want)
+	}
+
+	app := NewAppWithDriver(NewRecordingDriver())
+	events := recordEvents(app)
+	runFlowOn(t, app, flow("shfit"))
+	failed := events.named("task-error")
+	if len(failed) != 1 || failed[0].payload["taskID"] != "if" || !strings.Contains(failed[0].payload["error"].(string), `unknown key "shfit"`) {
+		t.Errorf("task-error events: %+v", failed)
+	}
+}
+
+func TestKeyConditionReadsKeyboard(t *testing.T) {
+	flow := graph([]testNode{
+		node("if", "IfNode", `{"condition":{"type":"key","key":"LShift","state":"down"}}`),
+		node("yes", "KeyTap", `{"key":"y"}`),
+		node("no", "KeyTap", `{"key":"n"}`),
+	}, "start->if", "if:true->yes", "if:false->no")
+
+	driver := NewRecordingDriver()
+	runFlowOn(t, NewAppWithDriver(driver), flow)
+	assertActions(t, driver.ActionStrings(), "keyTap(n)")
+
+	// Held by the user rather than the flow
+	driver = NewRecordingDriver()
+	driver.SetKeyDown("lshift", true)
+	runFlowOn(t, NewAppWithDriver(driver), flow)
+	assertActions(t, driver.ActionStrings(), "keyTap(y)")
+}
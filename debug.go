@@ -0,0 +1,154 @@
+// debug.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DebugMode selects where a debug run stops.
+type DebugMode string
+
+const (
+	// DebugOff runs without stopping.
+	DebugOff DebugMode = ""
+	// DebugStep stops before every node.
+	DebugStep DebugMode = "step"
+	// DebugBreakpoints stops only before nodes whose data has "breakpoint": true.
+	DebugBreakpoints DebugMode = "breakpoints"
+)
+
+// ParamsResolver is implemented by executors that can report the parameters
+// a node will run with, after defaults are applied. Debug runs include them
+// in execution-breakpoint events.
+type ParamsResolver interface {
+	ResolveParams(ec *ExecContext) (interface{}, error)
+}
+
+// hasBreakpoint reports whether a node is flagged with a breakpoint.
+func hasBreakpoint(node Node) bool {
+	flag, _ := node.Data["breakpoint"].(bool)
+	return flag
+}
+
+// debugger holds a run's tasks before they execute until the user steps or
+// continues. In parallel runs several tasks may be held at once; they are
+// released in the order they arrived.
+type debugger struct {
+	mu       sync.Mutex
+	mode     DebugMode
+	stepping bool // stop before the next node even without a breakpoint
+	waiters  []chan struct{}
+}
+
+func newDebugger(mode DebugMode) *debugger {
+	return &debugger{
+		mode:     mode,
+		stepping: mode == DebugStep,
+	}
+}
+
+// shouldBreak reports whether execution must stop before node.
+func (d *debugger) shouldBreak(node Node) bool {
+	if d.mode == DebugOff {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stepping || hasBreakpoint(node)
+}
+
+// wait blocks until the task is released by step or resume, or ctx is done.
+func (d *debugger) wait(ctx context.Context) error {
+	release := make(chan struct{})
+	d.mu.Lock()
+	d.waiters = append(d.waiters, release)
+	d.mu.Unlock()
+
+	select {
+	case <-release:
+		return nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		for i, w := range d.waiters {
+			if w == release {
+				d.waiters = append(d.waiters[:i], d.waiters[i+1:]...)
+				break
+			}
+		}
+		d.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release lets the oldest held task run. With step set, the run stops again
+// before the next node; otherwise it runs on to the next breakpoint.
+func (d *debugger) release(step bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.mode == DebugOff {
+		return errors.New("execution is not in debug mode")
+	}
+	if len(d.waiters) == 0 {
+		return errors.New("execution is not stopped at a breakpoint")
+	}
+	d.stepping = step
+	close(d.waiters[0])
+	d.waiters = d.waiters[1:]
+	return nil
+}
+
+// isHolding reports whether any task is stopped at a breakpoint.
+func (d *debugger) isHolding() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.waiters) > 0
+}
+
+// resolveParams resolves the parameters of ec's task, converting a panic
+// into an error as executeTask does, as it runs outside executeTask.
+func resolveParams(resolver ParamsResolver, ec *ExecContext) (params interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return resolver.ResolveParams(ec)
+}
+
+// breakBefore stops ec's task if the debugger requires it, reporting the
+// parameters the node is about to use.
+func (r *ExecutionRun) breakBefore(ec *ExecContext) error {
+	node := Node{ID: ec.Task.ID, Type: ec.Task.Type, Data: ec.Task.Data}
+	if !r.debug.shouldBreak(node) {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"nodeId": node.ID,
+		"type":   node.Type,
+	}
+	if executor, ok := lookupExecutor(node.Type); ok {
+		payload["description"] = executor.Describe(node)
+		if resolver, ok := executor.(ParamsResolver); ok {
+			params, err := resolveParams(resolver, ec)
+			if err != nil {
+				payload["paramsError"] = err.Error()
+			} else {
+				payload["params"] = params
+			}
+		}
+	}
+	if payload["params"] == nil && payload["paramsError"] == nil {
+		payload["params"] = node.Data
+	}
+
+	ec.Emit("execution-breakpoint", payload)
+	if err := r.debug.wait(ec.Context()); err != nil {
+		return fmt.Errorf("stopped at breakpoint: %w", err)
+	}
+	return nil
+}
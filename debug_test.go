@@ -0,0 +1,74 @@
+// debug_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreakpointStopsOnceBeforeRetries(t *testing.T) {
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	flow := strings.TrimSuffix(chain(
+		// Fails every attempt, as the variable is never set
+		node("a", "KeyTap", `{"key":"{{missing}}","breakpoint":true,
+			"onError":{"action":"continue","retries":3,"retryDelay":1}}`),
+		node("b", "KeyTap", `{"key":"b"}`),
+	), "}") + `,"options":{"debug":"breakpoints"}}`
+
+	if _, err := app.StartExecution(flow); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for app.GetIsExecuting() {
+		if time.Now().After(deadline) {
+			t.Fatal("run did not end")
+		}
+		// Continue from every breakpoint the run stops at
+		if app.ContinueExecution() != nil {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if got := events.named("execution-breakpoint"); len(got) != 1 {
+		t.Errorf("stopped at %d breakpoints, want 1", len(got))
+	}
+	failed := events.named("task-error")
+	if len(failed) != 1 || failed[0].payload["attempts"] != 4 {
+		t.Errorf("task-error events: %+v", failed)
+	}
+	assertActions(t, driver.ActionStrings(), "keyTap(b)")
+}
+
+func TestTaskStartsAfterBreakpoint(t *testing.T) {
+	app := NewAppWithDriver(NewRecordingDriver())
+	events := recordEvents(app)
+	flow := withOptions(chain(node("a", "KeyTap", `{"key":"a","breakpoint":true}`)), `{"debug":"breakpoints"}`)
+	if _, err := app.StartExecution(flow); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.wait("execution-breakpoint")) != 1 {
+		t.Fatal("the run did not stop at the breakpoint")
+	}
+	started := func() bool {
+		for _, e := range events.named("task-started") {
+			if e.payload["taskID"] == "a" {
+				return true
+			}
+		}
+		return false
+	}
+	if started() {
+		t.Error("task-started was emitted while stopped at the breakpoint")
+	}
+	if err := app.StepExecution(); err != nil {
+		t.Fatal(err)
+	}
+	waitIdle(t, app)
+	if !started() {
+		t.Error("no task-started after leaving the breakpoint")
+	}
+}
@@ -0,0 +1,478 @@
+// driver.go
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// InputDriver is the set of mouse, keyboard and screen operations the
+// execution engine depends on. The default implementation drives the real
+// desktop through robotgo; RecordingDriver captures the actions instead so
+// flows can be exercised without a display.
+type InputDriver interface {
+	// Move places the cursor at x, y immediately.
+	Move(x, y int)
+	// MoveSmooth glides the cursor to x, y. low and high bound the per-step
+	// randomisation and delay is the pause between steps in milliseconds.
+	MoveSmooth(x, y int, low, high float64, delay int) bool
+	MouseDown(button string) error
+	MouseUp(button string) error
+	Click(button string)
+	// Scroll scrolls by x columns and y lines.
+	Scroll(x, y int)
+	// ScrollDir scrolls amount lines in direction ("up", "down", "left", "right").
+	ScrollDir(amount int, direction string)
+	KeyTap(key string, modifiers ...string) error
+	KeyDown(key string, modifiers ...string) error
+	KeyUp(key string, modifiers ...string) error
+	TypeStr(text string)
+	// Location returns the current cursor position.
+	Location() (int, int)
+	// PixelColor returns the colour at x, y as a lowercase hex string without '#'.
+	PixelColor(x, y int) string
+	// MoveWithSpeed makes the whole of m with the pause after each mouse
+	// action set to m.Sleep, and restores defaultMouseSleep afterwards. The
+	// pause is global to the desktop, so it is one action: concurrent branches
+	// can't change the speed of each other's moves.
+	MoveWithSpeed(m MouseMotion) error
+}
+
+// defaultMouseSleep is the pause in milliseconds after each mouse action
+// outside of mouse moves.
+const defaultMouseSleep = 100
+
+// MouseMotion is a cursor move made at a given mouse speed.
+type MouseMotion struct {
+	X, Y int
+	// Smooth glides the cursor like MoveSmooth, with Low and High bounding
+	// the per-step randomisation and Delay the ms between steps. If the glide
+	// fails the cursor jumps instead.
+	Smooth    bool
+	Low, High float64
+	Delay     int
+	Drag      bool // hold the left button down during the move
+	Sleep     int  // ms paused after each mouse action during the move
+}
+
+//=============================================== robotgo ===============================================
+
+// robotgoDriver is the InputDriver backed by the real desktop.
+type robotgoDriver struct{}
+
+// NewRobotgoDriver returns the default desktop InputDriver.
+func NewRobotgoDriver() InputDriver {
+	return robotgoDriver{}
+}
+
+func (robotgoDriver) Move(x, y int) {
+	robotgo.Move(x, y)
+}
+
+func (robotgoDriver) MoveSmooth(x, y int, low, high float64, delay int) bool {
+	return robotgo.MoveSmooth(x, y, low, high, delay)
+}
+
+func (robotgoDriver) MouseDown(button string) error {
+	return robotgo.MouseDown(button)
+}
+
+func (robotgoDriver) MouseUp(button string) error {
+	return robotgo.MouseUp(button)
+}
+
+func (robotgoDriver) Click(button string) {
+	robotgo.Click(button)
+}
+
+func (robotgoDriver) Scroll(x, y int) {
+	robotgo.Scroll(x, y)
+}
+
+func (robotgoDriver) ScrollDir(amount int, direction string) {
+	robotgo.ScrollDir(amount, direction)
+}
+
+func (robotgoDriver) KeyTap(key string, modifiers ...string) error {
+	return robotgo.KeyTap(key, toInterfaces(modifiers)...)
+}
+
+func (robotgoDriver) KeyDown(key string, modifiers ...string) error {
+	return robotgo.KeyDown(key, toInterfaces(modifiers)...)
+}
+
+func (robotgoDriver) KeyUp(key string, modifiers ...string) error {
+	return robotgo.KeyUp(key, toInterfaces(modifiers)...)
+}
+
+func (robotgoDriver) TypeStr(text string) {
+	robotgo.TypeStr(text)
+}
+
+func (robotgoDriver) Location() (int, int) {
+	return robotgo.Location()
+}
+
+func (robotgoDriver) PixelColor(x, y int) string {
+	return strings.ToLower(robotgo.GetPixelColor(x, y))
+}
+
+func (d robotgoDriver) MoveWithSpeed(m MouseMotion) error {
+	robotgo.MouseSleep = m.Sleep
+	defer func() { robotgo.MouseSleep = defaultMouseSleep }()
+
+	if m.Drag {
+		if err := robotgo.MouseDown("left"); err != nil {
+			return fmt.Errorf("MouseDown failed: %v", err)
+		}
+	}
+	if !m.Smooth || !robotgo.MoveSmooth(m.X, m.Y, m.Low, m.High, m.Delay) {
+		robotgo.Move(m.X, m.Y)
+	}
+	if m.Drag {
+		if err := robotgo.MouseUp("left"); err != nil {
+			return fmt.Errorf("MouseUp failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// toInterfaces adapts a string slice to robotgo's variadic interface{} arguments.
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+//=============================================== Recording ===============================================
+
+// DriverAction is a single call made against a RecordingDriver.
+type DriverAction struct {
+	Op   string        `json:"op"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// String formats the action as op(arg, arg, ...), which keeps expected
+// sequences in tests short and readable.
+func (a DriverAction) String() string {
+	args := make([]string, len(a.Args))
+	for i, arg := range a.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("%s(%s)", a.Op, strings.Join(args, ", "))
+}
+
+// RecordingDriver is an InputDriver that performs no real input and instead
+// keeps an ordered log of every action. It tracks the cursor position so
+// relative behaviour (e.g. "start from the current mouse position") still
+// works, and serves pixel colours from a configurable map and screen captures
+// from a fixture image.
+type RecordingDriver struct {
+	mu           sync.Mutex
+	actions      []DriverAction
+	x, y         int
+	pixels       map[[2]int]string
+	screen       image.Image
+	window       WindowInfo
+	clipboard    string
+	clipboardErr error
+	keysDown     map[string]bool
+	// DefaultPixel is returned by PixelColor for coordinates without an explicit colour.
+	DefaultPixel string
+	// ScreenWidth and ScreenHeight are the screen size reported when no
+	// screen image is set.
+	ScreenWidth, ScreenHeight int
+}
+
+// NewRecordingDriver creates a RecordingDriver with the cursor at 0, 0.
+func NewRecordingDriver() *RecordingDriver {
+	return &RecordingDriver{
+		pixels:       make(map[[2]int]string),
+		keysDown:     make(map[string]bool),
+		DefaultPixel: "000000",
+		ScreenWidth:  1920,
+		ScreenHeight: 1080,
+	}
+}
+
+// Actions returns a copy of the recorded action log.
+func (d *RecordingDriver) Actions() []DriverAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DriverAction, len(d.actions))
+	copy(out, d.actions)
+	return out
+}
+
+// ActionStrings returns the recorded action log formatted with DriverAction.String.
+func (d *RecordingDriver) ActionStrings() []string {
+	actions := d.Actions()
+	out := make([]string, len(actions))
+	for i, a := range actions {
+		out[i] = a.String()
+	}
+	return out
+}
+
+// Reset clears the action log without moving the cursor.
+func (d *RecordingDriver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = nil
+}
+
+// SetLocation moves the simulated cursor without recording an action.
+func (d *RecordingDriver) SetLocation(x, y int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.x, d.y = x, y
+}
+
+// SetPixel sets the colour PixelColor reports for x, y.
+func (d *RecordingDriver) SetPixel(x, y int, hex string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pixels[[2]int{x, y}] = strings.ToLower(strings.TrimPrefix(hex, "#"))
+}
+
+func (d *RecordingDriver) record(op string, args ...interface{}) {
+	d.actions = append(d.actions, DriverAction{Op: op, Args: args})
+}
+
+func (d *RecordingDriver) Move(x, y int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.x, d.y = x, y
+	d.record("move", x, y)
+}
+
+func (d *RecordingDriver) MoveSmooth(x, y int, low, high float64, delay int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.x, d.y = x, y
+	d.record("moveSmooth", x, y, low, high, delay)
+	return true
+}
+
+func (d *RecordingDriver) MouseDown(button string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("mouseDown", button)
+	return nil
+}
+
+func (d *RecordingDriver) MouseUp(button string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("mouseUp", button)
+	return nil
+}
+
+func (d *RecordingDriver) Click(button string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("click", button)
+}
+
+func (d *RecordingDriver) Scroll(x, y int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("scroll", x, y)
+}
+
+func (d *RecordingDriver) ScrollDir(amount int, direction string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("scrollDir", amount, direction)
+}
+
+func (d *RecordingDriver) KeyTap(key string, modifiers ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("keyTap", keyWithModifiers(key, modifiers))
+	return nil
+}
+
+func (d *RecordingDriver) KeyDown(key string, modifiers ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("keyDown", keyWithModifiers(key, modifiers))
+	return nil
+}
+
+func (d *RecordingDriver) KeyUp(key string, modifiers ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("keyUp", keyWithModifiers(key, modifiers))
+	return nil
+}
+
+func (d *RecordingDriver) TypeStr(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("type", text)
+}
+
+func (d *RecordingDriver) Location() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.x, d.y
+}
+
+func (d *RecordingDriver) PixelColor(x, y int) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok := d.pixels[[2]int{x, y}]; ok {
+		return c
+	}
+	return d.DefaultPixel
+}
+
+// MoveWithSpeed records the speed change, the move and the restore as
+// consecutive actions.
+func (d *RecordingDriver) MoveWithSpeed(m MouseMotion) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record("mouseSleep", m.Sleep)
+	if m.Drag {
+		d.record("mouseDown", "left")
+	}
+	d.x, d.y = m.X, m.Y
+	if m.Smooth {
+		d.record("moveSmooth", m.X, m.Y, m.Low, m.High, m.Delay)
+	} else {
+		d.record("move", m.X, m.Y)
+	}
+	if m.Drag {
+		d.record("mouseUp", "left")
+	}
+	d.record("mouseSleep", defaultMouseSleep)
+	return nil
+}
+
+// keyWithModifiers renders a key and its modifiers as "ctrl+shift+t".
+func keyWithModifiers(key string, modifiers []string) string {
+	if len(modifiers) == 0 {
+		return key
+	}
+	return strings.Join(append(append([]string{}, modifiers...), key), "+")
+}
+
+//=============================================== Held Input ===============================================
+
+// heldInput wraps an InputDriver and remembers which keys and mouse buttons
+// the flow is holding down, so conditions can test them.
+type heldInput struct {
+	InputDriver
+	mu      sync.Mutex
+	keys    map[string]bool
+	buttons map[string]bool
+}
+
+func newHeldInput(driver InputDriver) *heldInput {
+	return &heldInput{
+		InputDriver: driver,
+		keys:        make(map[string]bool),
+		buttons:     make(map[string]bool),
+	}
+}
+
+func (h *heldInput) MouseDown(button string) error {
+	if err := h.InputDriver.MouseDown(button); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buttons[button] = true
+	return nil
+}
+
+func (h *heldInput) MouseUp(button string) error {
+	err := h.InputDriver.MouseUp(button)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.buttons, button)
+	return err
+}
+
+// KeyDown holds key and its modifiers.
+func (h *heldInput) KeyDown(key string, modifiers ...string) error {
+	if err := h.InputDriver.KeyDown(key, modifiers...); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range append([]string{key}, modifiers...) {
+		h.keys[strings.ToLower(k)] = true
+	}
+	return nil
+}
+
+// KeyUp releases key and its modifiers.
+func (h *heldInput) KeyUp(key string, modifiers ...string) error {
+	err := h.InputDriver.KeyUp(key, modifiers...)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range append([]string{key}, modifiers...) {
+		delete(h.keys, strings.ToLower(k))
+	}
+	return err
+}
+
+// releaseAll lets go of every key and button the flow is holding and
+// returns their names.
+func (h *heldInput) releaseAll() []string {
+	h.mu.Lock()
+	buttons := h.buttons
+	h.buttons = make(map[string]bool)
+	h.mu.Unlock()
+
+	released := h.releaseKeys()
+	for button := range buttons {
+		h.InputDriver.MouseUp(button)
+		released = append(released, button)
+	}
+	sort.Strings(released)
+	return released
+}
+
+// releaseKeys lets go of every key the flow is holding, modifiers last, and
+// returns their names.
+func (h *heldInput) releaseKeys() []string {
+	h.mu.Lock()
+	keys := h.keys
+	h.keys = make(map[string]bool)
+	h.mu.Unlock()
+
+	var released []string
+	for key := range keys {
+		if !isModifier(key) {
+			released = append(released, key)
+		}
+	}
+	sort.Strings(released)
+	for _, m := range recordModifiers {
+		if keys[m] {
+			released = append(released, m)
+		}
+	}
+	for _, key := range released {
+		h.InputDriver.KeyUp(key)
+	}
+	return released
+}
+
+// IsKeyHeld reports whether the flow is holding key down.
+func (h *heldInput) IsKeyHeld(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.keys[strings.ToLower(key)]
+}
@@ -0,0 +1,60 @@
+// errorpolicy.go
+
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// HandleError is the output handle that runs when a node with the "error"
+// policy fails. Any node can have it, besides its own handles.
+const HandleError = "error"
+
+// Error policy actions, applied once a node has used up its retries.
+const (
+	OnErrorAbort    = "abort"    // stop the run and emit execution-failed
+	OnErrorContinue = "continue" // carry on as if the node had succeeded
+	OnErrorRoute    = "error"    // follow only the error handle
+)
+
+// ErrorPolicy is what happens when a node fails. It is read from the
+// "onError" object of any node's data.
+type ErrorPolicy struct {
+	Action     string  `json:"action"`
+	Retries    int     `json:"retries"`
+	RetryDelay float64 `json:"retryDelay"` // ms before the first retry
+	Backoff    float64 `json:"backoff"`    // multiplies the delay after each retry
+	MaxDelay   float64 `json:"maxDelay"`   // ms, caps the delay; 0 for no cap
+}
+
+func readErrorPolicy(data map[string]interface{}) (ErrorPolicy, error) {
+	r := newDataReader(data).Object("onError")
+	p := ErrorPolicy{
+		Action:     r.OneOf("action", OnErrorAbort, OnErrorAbort, OnErrorContinue, OnErrorRoute),
+		Retries:    r.Int("retries", 0),
+		RetryDelay: r.Float("retryDelay", 1000),
+		Backoff:    r.Float("backoff", 2),
+		MaxDelay:   r.Float("maxDelay", 0),
+	}
+	r.Min("retries", float64(p.Retries), 0)
+	r.Min("retryDelay", p.RetryDelay, 0)
+	r.Min("backoff", p.Backoff, 1)
+	r.Min("maxDelay", p.MaxDelay, 0)
+	return p, r.Err()
+}
+
+// nodeErrorPolicy returns the error policy of a validated node.
+func nodeErrorPolicy(node Node) ErrorPolicy {
+	p, _ := readErrorPolicy(node.Data)
+	return p
+}
+
+// delay returns how long to wait before retry number attempt, counting from 1.
+func (p ErrorPolicy) delay(attempt int) time.Duration {
+	ms := p.RetryDelay * math.Pow(p.Backoff, float64(attempt-1))
+	if p.MaxDelay > 0 && ms > p.MaxDelay {
+		ms = p.MaxDelay
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
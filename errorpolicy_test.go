@@ -0,0 +1,128 @@
+// errorpolicy_test.go
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterExecutor("FlakyNode", flakyExecutor{})
+}
+
+// flakyExecutor taps "f" each time it runs, and fails the first "failures"
+// times within a run.
+type flakyExecutor struct{}
+
+func (flakyExecutor) Validate(node Node) error { return nil }
+
+func (flakyExecutor) Describe(node Node) string { return "Fail a few times" }
+
+func (flakyExecutor) Execute(ec *ExecContext) error {
+	state := ec.State()
+	calls, _ := state["calls"].(int)
+	calls++
+	state["calls"] = calls
+	ec.Driver.KeyTap("f")
+	failures, _ := ec.Task.Data["failures"].(float64)
+	if calls <= int(failures) {
+		return fmt.Errorf("failure %d", calls)
+	}
+	return nil
+}
+
+// flaky is a FlakyNode that fails failures times, with onError as its policy.
+func flaky(failures int, onError string) testNode {
+	return node("flaky", "FlakyNode", fmt.Sprintf(`{"failures":%d,"onError":%s}`, failures, onError))
+}
+
+func TestErrorPolicyAbort(t *testing.T) {
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, chain(flaky(5, `{"retries":1,"retryDelay":0}`), node("next", "KeyTap", `{"key":"n"}`)))
+
+	assertActions(t, driver.ActionStrings(), "keyTap(f)", "keyTap(f)")
+	failed := events.wait("execution-failed")
+	if len(failed) != 1 || failed[0].payload["taskID"] != "flaky" || failed[0].payload["attempts"] != 2 ||
+		failed[0].payload["error"] != "failure 2" {
+		t.Errorf("execution-failed events: %+v", failed)
+	}
+	if n := len(events.named("execution-completed")); n != 0 {
+		t.Errorf("%d execution-completed events after a failure", n)
+	}
+}
+
+func TestErrorPolicyContinue(t *testing.T) {
+	flow := graph([]testNode{
+		flaky(5, `{"action":"continue"}`),
+		node("if", "IfNode", `{"condition":{"type":"result","status":"error"}}`),
+		node("failed", "KeyTap", `{"key":"e"}`),
+		node("succeeded", "KeyTap", `{"key":"s"}`),
+	}, "start->flaky", "flaky->if", "if:true->failed", "if:false->succeeded")
+
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, flow)
+
+	assertActions(t, driver.ActionStrings(), "keyTap(f)", "keyTap(e)")
+	if errs := events.named("task-error"); len(errs) != 1 || errs[0].payload["action"] != OnErrorContinue {
+		t.Errorf("task-error events: %+v", errs)
+	}
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
+
+func TestErrorPolicyRoute(t *testing.T) {
+	flow := func(failures int) string {
+		return graph([]testNode{
+			flaky(failures, `{"action":"error","retries":1,"retryDelay":0}`),
+			node("handler", "KeyTap", `{"key":"h"}`),
+			node("next", "KeyTap", `{"key":"n"}`),
+			node("join", "KeyTap", `{"key":"j"}`),
+		}, "start->flaky", "flaky:error->handler", "flaky->next", "handler->join", "next->join")
+	}
+	tests := []struct {
+		name     string
+		failures int
+		want     []string
+	}{
+		{"fails", 2, []string{"keyTap(f)", "keyTap(f)", "keyTap(h)", "keyTap(j)"}},
+		{"succeeds on retry", 1, []string{"keyTap(f)", "keyTap(f)", "keyTap(n)", "keyTap(j)"}},
+		{"succeeds", 0, []string{"keyTap(f)", "keyTap(n)", "keyTap(j)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertActions(t, runFlow(t, flow(tt.failures)), tt.want...)
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	began := time.Now()
+	runFlowOn(t, app, chain(flaky(3, `{"retries":3,"retryDelay":40,"backoff":2,"maxDelay":100}`)))
+	elapsed := time.Since(began)
+
+	assertActions(t, driver.ActionStrings(), "keyTap(f)", "keyTap(f)", "keyTap(f)", "keyTap(f)")
+	var delays []interface{}
+	for _, e := range events.named("task-retry") {
+		delays = append(delays, e.payload["delay"])
+	}
+	if want := []interface{}{int64(40), int64(80), int64(100)}; !slices.Equal(delays, want) {
+		t.Errorf("retry delays %v, want %v", delays, want)
+	}
+	if elapsed < 220*time.Millisecond {
+		t.Errorf("run took %v, less than the retry delays", elapsed)
+	}
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
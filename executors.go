@@ -0,0 +1,260 @@
+// executors.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NodeExecutor implements a single node type. Executors register themselves
+// with RegisterExecutor, so adding a node type never touches the scheduler.
+type NodeExecutor interface {
+	// Validate checks the node's configuration before a run starts.
+	Validate(node Node) error
+	// Execute performs the node's action.
+	Execute(ec *ExecContext) error
+	// Describe summarises in one sentence what the node will do.
+	Describe(node Node) string
+}
+
+// BranchingExecutor is implemented by executors with labelled outputs. An
+// edge leaving one of the declared handles only runs if Execute selected that
+// handle with ExecContext.Follow; the others are skipped along with whatever
+// depends only on them. Edges on undeclared handles always run.
+type BranchingExecutor interface {
+	Handles(node Node) []string
+}
+
+// AssigningExecutor is implemented by executors that set run variables, so
+// validation knows which {{name}} templates refer to a variable.
+type AssigningExecutor interface {
+	Assigns(node Node) []string
+}
+
+// ExecContext is everything an executor may use while running a task.
+type ExecContext struct {
+	Task      Task
+	Driver    InputDriver
+	Screen    ScreenCapturer // nil if the driver can't capture the screen
+	Windows   WindowProvider // nil if the driver can't report the active window
+	Clipboard Clipboard      // nil if the driver can't reach the clipboard
+	KeyState  KeyStateReader // nil if the driver can't read the keyboard
+	ctx       context.Context
+	run       *ExecutionRun
+
+	handles []string
+}
+
+// Context is cancelled when the task must stop, e.g. because the run was stopped.
+func (ec *ExecContext) Context() context.Context {
+	return ec.ctx
+}
+
+// Sleep waits for d, stopping the clock while the run is paused. Executors
+// must use it instead of time.Sleep so that stopping and pausing are prompt.
+func (ec *ExecContext) Sleep(d time.Duration) error {
+	return ec.run.gate.Sleep(ec.ctx, d)
+}
+
+// RunID returns the ID of the run the task belongs to.
+func (ec *ExecContext) RunID() string {
+	return ec.run.ID
+}
+
+// Follow selects the output handles whose edges run after this node. Only
+// meaningful for a BranchingExecutor; a later call replaces the selection.
+func (ec *ExecContext) Follow(handles ...string) {
+	ec.handles = handles
+}
+
+// State returns storage that persists across executions of this node within
+// the run, such as a loop's iteration count. It is cleared when an enclosing
+// loop starts its next iteration.
+func (ec *ExecContext) State() map[string]interface{} {
+	return ec.run.nodeStateFor(ec.Task.ID)
+}
+
+// Variable returns the value of a run variable and whether it is set.
+func (ec *ExecContext) Variable(name string) (interface{}, bool) {
+	return ec.run.vars.Get(name)
+}
+
+// SetVariable assigns a run variable, visible to every later node.
+func (ec *ExecContext) SetVariable(name string, value interface{}) {
+	ec.run.vars.Set(name, value)
+}
+
+// Expand replaces {{name}} templates in s with run variables or the
+// built-ins iteration, run_id, mouse.x and mouse.y.
+func (ec *ExecContext) Expand(s string) (string, error) {
+	return expandTemplate(s, ec.lookupTemplate)
+}
+
+// ExpandData returns a copy of data with the templates in every string expanded.
+func (ec *ExecContext) ExpandData(data map[string]interface{}) (map[string]interface{}, error) {
+	expanded, err := expandValue(data, ec.lookupTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(map[string]interface{}), nil
+}
+
+func (ec *ExecContext) lookupTemplate(name string) (interface{}, bool) {
+	switch name {
+	case "iteration":
+		return ec.run.iteration(ec.Task.ID), true
+	case "run_id":
+		return ec.run.ID, true
+	case "mouse.x":
+		x, _ := ec.Driver.Location()
+		return x, true
+	case "mouse.y":
+		_, y := ec.Driver.Location()
+		return y, true
+	}
+	return ec.Variable(name)
+}
+
+// Emit sends an event to the frontend, tagged with the run and task IDs.
+func (ec *ExecContext) Emit(event string, payload map[string]interface{}) {
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+	payload["taskID"] = ec.Task.ID
+	ec.run.emit(event, payload)
+}
+
+var (
+	executorsMu sync.RWMutex
+	executors   = make(map[string]NodeExecutor)
+)
+
+// RegisterExecutor makes executor available for nodes of nodeType. It panics
+// if the type is registered twice, as that is always a programming error.
+func RegisterExecutor(nodeType string, executor NodeExecutor) {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	if executor == nil {
+		panic("RegisterExecutor: executor is nil for " + nodeType)
+	}
+	if _, dup := executors[nodeType]; dup {
+		panic("RegisterExecutor: executor already registered for " + nodeType)
+	}
+	executors[nodeType] = executor
+}
+
+// lookupExecutor returns the executor registered for nodeType.
+func lookupExecutor(nodeType string) (NodeExecutor, bool) {
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+	executor, ok := executors[nodeType]
+	return executor, ok
+}
+
+// RegisteredNodeTypes lists the node types that have an executor, sorted.
+func RegisteredNodeTypes() []string {
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+	types := make([]string, 0, len(executors))
+	for t := range executors {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// validateNode checks that a node has a registered executor and that the
+// executor accepts its data, including its error policy and time limit.
+func validateNode(node Node) error {
+	executor, ok := lookupExecutor(node.Type)
+	if !ok {
+		return fmt.Errorf("node %s: unknown node type %q", node.ID, node.Type)
+	}
+	err := executor.Validate(node)
+	if err == nil {
+		_, err = readErrorPolicy(node.Data)
+	}
+	if err == nil {
+		_, _, err = readNodeTimeout(node.Data)
+	}
+	if err != nil {
+		return fmt.Errorf("node %s (%s): %w", node.ID, node.Type, err)
+	}
+	return nil
+}
+
+// handlesOf returns the handles node's executor declares, if it branches.
+func handlesOf(node Node) []string {
+	executor, ok := lookupExecutor(node.Type)
+	if !ok {
+		return nil
+	}
+	if branching, ok := executor.(BranchingExecutor); ok {
+		return branching.Handles(node)
+	}
+	return nil
+}
+
+// assignsOf returns the variables node's executor may set.
+func assignsOf(node Node) []string {
+	executor, ok := lookupExecutor(node.Type)
+	if !ok {
+		return nil
+	}
+	if assigning, ok := executor.(AssigningExecutor); ok {
+		return assigning.Assigns(node)
+	}
+	return nil
+}
+
+// newExecContext returns the context task runs with, under the run's
+// context until executeTask applies the node timeout.
+func newExecContext(task Task, run *ExecutionRun) *ExecContext {
+	return &ExecContext{
+		Task:      task,
+		Driver:    run.driver,
+		Screen:    run.app.screen,
+		Windows:   run.app.windows,
+		Clipboard: run.app.clipboard,
+		KeyState:  run.app.keyState,
+		ctx:       run.ctx,
+		run:       run,
+	}
+}
+
+// executeTask runs a task through its registered executor, converting
+// panics into errors so one bad node cannot take down a worker. It returns
+// the output handles the executor selected. A non-zero timeout cancels the
+// executor's context and fails it with errTaskTimeout.
+func executeTask(task Task, run *ExecutionRun, timeout time.Duration) (handles []string, err error) {
+	log.Printf("Starting execution of task ID: %s, Type: %s", task.ID, task.Type)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in task %s: %v", task.ID, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+		log.Printf("Completed execution of task ID: %s", task.ID)
+	}()
+
+	executor, ok := lookupExecutor(task.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown task type: %s", task.Type)
+	}
+	log.Printf("%s: %s", task.ID, executor.Describe(Node{ID: task.ID, Type: task.Type, Data: task.Data}))
+
+	ec := newExecContext(task, run)
+	ctx, cancel := run.withTimeout(run.ctx, timeout)
+	defer cancel()
+	ec.ctx = ctx
+	err = executor.Execute(ec)
+	if errors.Is(context.Cause(ctx), errTaskTimeout) {
+		err = fmt.Errorf("%w after %v", errTaskTimeout, timeout)
+	}
+	return ec.handles, err
+}
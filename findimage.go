@@ -0,0 +1,247 @@
+// findimage.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Keypress/utils"
+)
+
+func init() {
+	RegisterExecutor("FindImageNode", findImageExecutor{})
+}
+
+// FindImageNode output handles in branch mode.
+const (
+	HandleFound    = "found"
+	HandleNotFound = "notFound"
+)
+
+// FindImageData is the data of a FindImageNode. Times are in milliseconds.
+type FindImageData struct {
+	Mode           string  `json:"mode"`      // "wait" or "branch"
+	Image          string  `json:"image"`     // file path, relative to the templates directory unless absolute
+	ImageData      string  `json:"imageData"` // base64 PNG or JPEG, optionally as a data URL
+	Region         Rect    `json:"region"`    // area to search; empty for the whole screen
+	Threshold      float64 `json:"threshold"` // similarity from 0 to 1
+	PollInterval   float64 `json:"pollInterval"`
+	Timeout        float64 `json:"timeout"`        // 0: wait forever (wait) or search once (branch)
+	OutputVariable string  `json:"outputVariable"` // prefix of the variables the match is stored in
+	MoveMouse      bool    `json:"moveMouse"`
+}
+
+func decodeFindImageData(data map[string]interface{}) (FindImageData, error) {
+	r := newDataReader(data).AllowTemplates()
+	region := r.Object("region")
+	d := FindImageData{
+		Mode:      r.OneOf("mode", "wait", "wait", "branch"),
+		Image:     r.String("image", ""),
+		ImageData: r.String("imageData", ""),
+		Region: Rect{
+			X:      region.Int("x", 0),
+			Y:      region.Int("y", 0),
+			Width:  region.Int("width", 0),
+			Height: region.Int("height", 0),
+		},
+		Threshold:      r.Float("threshold", 0.9),
+		PollInterval:   r.Float("pollInterval", 500),
+		OutputVariable: r.String("outputVariable", "match"),
+		MoveMouse:      r.Bool("moveMouse", false),
+	}
+	defaultTimeout := 10000.0
+	if d.Mode == "branch" {
+		defaultTimeout = 0
+	}
+	d.Timeout = r.Float("timeout", defaultTimeout)
+
+	if d.Image == "" && d.ImageData == "" {
+		r.Fail("image", "string", nil, "set image or imageData")
+	}
+	if d.Threshold < 0 || d.Threshold > 1 {
+		r.Fail("threshold", "number between 0 and 1", d.Threshold, "")
+	}
+	region.Min("width", float64(d.Region.Width), 0)
+	region.Min("height", float64(d.Region.Height), 0)
+	r.Min("pollInterval", d.PollInterval, 1)
+	r.Min("timeout", d.Timeout, 0)
+	return d, r.Err()
+}
+
+// loadTemplateImage decodes the image a FindImageNode searches for.
+func loadTemplateImage(d FindImageData) (image.Image, error) {
+	var raw []byte
+	if d.ImageData != "" {
+		encoded := d.ImageData
+		// Accept data URLs as produced by the browser
+		if i := strings.Index(encoded, ","); strings.HasPrefix(encoded, "data:") && i >= 0 {
+			encoded = encoded[i+1:]
+		}
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("invalid imageData: %w", err)
+		}
+	} else {
+		path := d.Image
+		if !filepath.IsAbs(path) {
+			dir, err := utils.GetTemplatesDir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(dir, path)
+		}
+		var err error
+		if raw, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read template image: %w", err)
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template image: %w", err)
+	}
+	return img, nil
+}
+
+// findImageExecutor searches the screen for a template image and reports
+// where it is, waiting for it to appear or branching on whether it did.
+type findImageExecutor struct{}
+
+func (findImageExecutor) Validate(node Node) error {
+	_, err := decodeFindImageData(node.Data)
+	return err
+}
+
+func (findImageExecutor) Handles(node Node) []string {
+	d, _ := decodeFindImageData(node.Data)
+	if d.Mode != "branch" {
+		return nil
+	}
+	return []string{HandleFound, HandleNotFound}
+}
+
+func (findImageExecutor) Assigns(node Node) []string {
+	d, _ := decodeFindImageData(node.Data)
+	if d.OutputVariable == "" {
+		return nil
+	}
+	return []string{d.OutputVariable + ".x", d.OutputVariable + ".y", d.OutputVariable + ".score"}
+}
+
+func (findImageExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFindImageData(data)
+}
+
+func (findImageExecutor) Describe(node Node) string {
+	d, _ := decodeFindImageData(node.Data)
+	name := "an image"
+	if d.Image != "" {
+		name = d.Image
+	}
+	if d.Mode == "branch" {
+		return fmt.Sprintf("Branch on whether %s is on screen", name)
+	}
+	return fmt.Sprintf("Wait for %s to appear on screen", name)
+}
+
+func (findImageExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeFindImageData(data)
+	if err != nil {
+		return err
+	}
+	if ec.Screen == nil {
+		return errors.New("screen capture is not available with this input driver")
+	}
+	tmpl, err := loadTemplateImage(d)
+	if err != nil {
+		return err
+	}
+
+	match, found, err := searchScreen(ec, d, tmpl)
+	if err != nil {
+		return err
+	}
+	ec.Emit("image-search", map[string]interface{}{
+		"found": found,
+		"x":     match.X,
+		"y":     match.Y,
+		"score": match.Score,
+	})
+
+	if found {
+		// Report the centre of the match, which is where a click should land
+		size := tmpl.Bounds().Size()
+		x, y := match.X+size.X/2, match.Y+size.Y/2
+		log.Printf("Found template at %d, %d (score %.3f)", x, y, match.Score)
+		if d.OutputVariable != "" {
+			ec.SetVariable(d.OutputVariable+".x", x)
+			ec.SetVariable(d.OutputVariable+".y", y)
+			ec.SetVariable(d.OutputVariable+".score", match.Score)
+		}
+		if d.MoveMouse {
+			ec.Driver.Move(x, y)
+		}
+	}
+
+	if d.Mode == "branch" {
+		handle := HandleNotFound
+		if found {
+			handle = HandleFound
+		}
+		followBranch(ec, handle)
+		return nil
+	}
+	if !found {
+		return fmt.Errorf("image not found after %v ms (best score %.3f, threshold %v)", d.Timeout, match.Score, d.Threshold)
+	}
+	return nil
+}
+
+// searchScreen captures the screen and looks for tmpl until a match scores
+// at least the threshold or the timeout has passed, returning the best match
+// of the last search. Time spent paused doesn't count towards the timeout.
+func searchScreen(ec *ExecContext, d FindImageData, tmpl image.Image) (imageMatch, bool, error) {
+	timeout := time.Duration(d.Timeout * float64(time.Millisecond))
+	interval := time.Duration(d.PollInterval * float64(time.Millisecond))
+	var elapsed time.Duration
+	for {
+		start := time.Now()
+		shot, err := ec.Screen.Capture(d.Region)
+		if err != nil {
+			return imageMatch{}, false, fmt.Errorf("screen capture failed: %w", err)
+		}
+		match, ok := findTemplate(shot, tmpl)
+		if ok && match.Score >= d.Threshold {
+			return match, true, nil
+		}
+		elapsed += time.Since(start)
+
+		searchOnce := timeout == 0 && d.Mode == "branch"
+		if searchOnce || (timeout > 0 && elapsed >= timeout) {
+			return match, false, nil
+		}
+		if err := ec.Sleep(interval); err != nil {
+			return match, false, err
+		}
+		elapsed += interval
+	}
+}
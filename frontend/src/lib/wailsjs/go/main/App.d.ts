@@ -2,12 +2,38 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function ContinueExecution():Promise<void>;
+
+export function GetInputHooksSupported():Promise<boolean>;
+
 export function GetIsExecuting():Promise<boolean>;
 
+export function GetIsPaused():Promise<boolean>;
+
+export function GetIsRecording():Promise<boolean>;
+
+export function GetSettings():Promise<main.Settings>;
+
 export function LoadLastFile():Promise<main.FlowData>;
 
+export function PauseExecution():Promise<void>;
+
+export function ResumeExecution():Promise<void>;
+
 export function SaveFile(arg1:main.FlowData):Promise<string>;
 
-export function StartExecution(arg1:string):Promise<void>;
+export function SaveFlowAs(arg1:string,arg2:main.FlowData):Promise<string>;
+
+export function SaveSettings(arg1:main.Settings):Promise<void>;
+
+export function StartExecution(arg1:string):Promise<string>;
+
+export function StartRecording():Promise<void>;
+
+export function StepExecution():Promise<void>;
 
 export function StopExecution():Promise<void>;
+
+export function StopRecording():Promise<main.FlowData>;
+
+export function ValidateFlow(arg1:main.FlowData):Promise<main.ValidationReport>;
@@ -2,22 +2,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ContinueExecution() {
+  return window['go']['main']['App']['ContinueExecution']();
+}
+
+export function GetInputHooksSupported() {
+  return window['go']['main']['App']['GetInputHooksSupported']();
+}
+
 export function GetIsExecuting() {
   return window['go']['main']['App']['GetIsExecuting']();
 }
 
+export function GetIsPaused() {
+  return window['go']['main']['App']['GetIsPaused']();
+}
+
+export function GetIsRecording() {
+  return window['go']['main']['App']['GetIsRecording']();
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
 export function LoadLastFile() {
   return window['go']['main']['App']['LoadLastFile']();
 }
 
+export function PauseExecution() {
+  return window['go']['main']['App']['PauseExecution']();
+}
+
+export function ResumeExecution() {
+  return window['go']['main']['App']['ResumeExecution']();
+}
+
 export function SaveFile(arg1) {
   return window['go']['main']['App']['SaveFile'](arg1);
 }
 
+export function SaveFlowAs(arg1,arg2) {
+  return window['go']['main']['App']['SaveFlowAs'](arg1,arg2);
+}
+
+export function SaveSettings(arg1) {
+  return window['go']['main']['App']['SaveSettings'](arg1);
+}
+
 export function StartExecution(arg1) {
   return window['go']['main']['App']['StartExecution'](arg1);
 }
 
+export function StartRecording() {
+  return window['go']['main']['App']['StartRecording']();
+}
+
+export function StepExecution() {
+  return window['go']['main']['App']['StepExecution']();
+}
+
 export function StopExecution() {
   return window['go']['main']['App']['StopExecution']();
 }
+
+export function StopRecording() {
+  return window['go']['main']['App']['StopRecording']();
+}
+
+export function ValidateFlow(arg1) {
+  return window['go']['main']['App']['ValidateFlow'](arg1);
+}
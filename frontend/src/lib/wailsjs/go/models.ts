@@ -3,6 +3,7 @@ export namespace main {
 	export class Edge {
 	    id: string;
 	    source: string;
+	    sourceHandle?: string;
 	    target: string;
 	
 	    static createFrom(source: any = {}) {
@@ -13,9 +14,28 @@ export namespace main {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
 	        this.source = source["source"];
+	        this.sourceHandle = source["sourceHandle"];
 	        this.target = source["target"];
 	    }
 	}
+	export class FieldError {
+	    path: string;
+	    expected: string;
+	    actual: any;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.expected = source["expected"];
+	        this.actual = source["actual"];
+	        this.message = source["message"];
+	    }
+	}
 	export class Node {
 	    id: string;
 	    type: string;
@@ -66,6 +86,162 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class FlowIssue {
+	    severity: string;
+	    code: string;
+	    message: string;
+	    nodeIds?: string[];
+	    edgeId?: string;
+	    fields?: FieldError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FlowIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.severity = source["severity"];
+	        this.code = source["code"];
+	        this.message = source["message"];
+	        this.nodeIds = source["nodeIds"];
+	        this.edgeId = source["edgeId"];
+	        this.fields = this.convertValues(source["fields"], FieldError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HotkeySettings {
+	    start: string;
+	    stop: string;
+	    pause: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HotkeySettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.stop = source["stop"];
+	        this.pause = source["pause"];
+	    }
+	}
+	export class SafetySettings {
+	    enabled: boolean;
+	    corners: boolean;
+	    interference: boolean;
+	    tolerance: number;
+	    pollInterval: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SafetySettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.corners = source["corners"];
+	        this.interference = source["interference"];
+	        this.tolerance = source["tolerance"];
+	        this.pollInterval = source["pollInterval"];
+	    }
+	}
+	export class TimeoutSettings {
+	    run: number;
+	    node: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeoutSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.run = source["run"];
+	        this.node = source["node"];
+	    }
+	}
+	export class Settings {
+	    hotkeys: HotkeySettings;
+	    safety: SafetySettings;
+	    timeouts: TimeoutSettings;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hotkeys = this.convertValues(source["hotkeys"], HotkeySettings);
+	        this.safety = this.convertValues(source["safety"], SafetySettings);
+	        this.timeouts = this.convertValues(source["timeouts"], TimeoutSettings);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ValidationReport {
+	    valid: boolean;
+	    issues: FlowIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ValidationReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.valid = source["valid"];
+	        this.issues = this.convertValues(source["issues"], FlowIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
@@ -21,10 +21,11 @@ interface Backend {
     TypeString(text: string): Promise<void>;
     KeyTap(key: string): Promise<void>;
     GetMousePosition(): Promise<MousePosition>;
-    StartExecution(nodesJSON: string): Promise<void>;
+    StartExecution(nodesJSON: string): Promise<string>;
+    GetInputHooksSupported(): Promise<boolean>;
 
     // methods for task and execution events
-    StartExecution(data: string): Promise<void>;
+    StartExecution(data: string): Promise<string>;
     OnTaskStarted(callback: (taskId: string) => void): void;
     OnTaskCompleted(callback: (taskId: string) => void): void;
     OnTaskError(callback: (payload: { taskID: string; error: string }) => void): void;
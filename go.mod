@@ -7,6 +7,7 @@ toolchain go1.23.2
 require (
 	github.com/adrg/xdg v0.5.3
 	github.com/go-vgo/robotgo v0.110.5
+	github.com/google/uuid v1.6.0
 	github.com/wailsapp/wails/v2 v2.9.2
 )
 
@@ -17,7 +18,6 @@ require (
 	github.com/gen2brain/shm v0.1.1 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/jchv/go-winloader v0.0.0-20210711035445-715c2860da7e // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/kbinani/screenshot v0.0.0-20240820160931-a8a2c5d0e191 // indirect
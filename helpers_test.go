@@ -0,0 +1,157 @@
+// helpers_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNode is a node for chain, with its data as JSON.
+type testNode struct {
+	id, typ, data string
+}
+
+func node(id, typ, data string) testNode {
+	return testNode{id: id, typ: typ, data: data}
+}
+
+// chain returns flowchart JSON that runs a StartNode and then nodes in order.
+func chain(nodes ...testNode) string {
+	parts := []string{`{"id":"start","type":"StartNode","data":{}}`}
+	var edges []string
+	prev := "start"
+	for _, n := range nodes {
+		parts = append(parts, fmt.Sprintf(`{"id":%q,"type":%q,"data":%s}`, n.id, n.typ, n.data))
+		edges = append(edges, fmt.Sprintf(`{"id":"%s-%s","source":%q,"target":%q}`, prev, n.id, prev, n.id))
+		prev = n.id
+	}
+	return fmt.Sprintf(`{"nodes":[%s],"edges":[%s]}`, strings.Join(parts, ","), strings.Join(edges, ","))
+}
+
+// graph returns flowchart JSON for a StartNode and nodes, joined by edges
+// written "source->target" or "source:handle->target".
+func graph(nodes []testNode, edges ...string) string {
+	parts := []string{`{"id":"start","type":"StartNode","data":{}}`}
+	for _, n := range nodes {
+		parts = append(parts, fmt.Sprintf(`{"id":%q,"type":%q,"data":%s}`, n.id, n.typ, n.data))
+	}
+	edgeParts := make([]string, len(edges))
+	for i, e := range edges {
+		source, target, _ := strings.Cut(e, "->")
+		source, handle, _ := strings.Cut(source, ":")
+		edgeParts[i] = fmt.Sprintf(`{"id":"e%d","source":%q,"sourceHandle":%q,"target":%q}`, i, source, handle, target)
+	}
+	return fmt.Sprintf(`{"nodes":[%s],"edges":[%s]}`, strings.Join(parts, ","), strings.Join(edgeParts, ","))
+}
+
+// withOptions adds run options, as JSON, to flowchart JSON.
+func withOptions(flow, options string) string {
+	return strings.TrimSuffix(flow, "}") + `,"options":` + options + "}"
+}
+
+// parseFlow decodes flowchart JSON for ValidateFlow.
+func parseFlow(t *testing.T, flow string) FlowData {
+	t.Helper()
+	var data FlowData
+	if err := json.Unmarshal([]byte(flow), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// runFlow runs flow on a new App over a RecordingDriver and returns the
+// actions it performed.
+func runFlow(t *testing.T, flow string) []string {
+	t.Helper()
+	driver := NewRecordingDriver()
+	runFlowOn(t, NewAppWithDriver(driver), flow)
+	return driver.ActionStrings()
+}
+
+// runFlowOn starts flow on app and waits for the run to end.
+func runFlowOn(t *testing.T, app *App, flow string) {
+	t.Helper()
+	if _, err := app.StartExecution(flow); err != nil {
+		t.Fatal(err)
+	}
+	waitIdle(t, app)
+}
+
+// waitIdle waits for the current run of app to end.
+func waitIdle(t *testing.T, app *App) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for app.GetIsExecuting() {
+		if time.Now().After(deadline) {
+			t.Fatal("run did not end")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// withoutOp drops actions of the given op, e.g. "mouseSleep".
+func withoutOp(actions []string, op string) []string {
+	return slices.DeleteFunc(slices.Clone(actions), func(a string) bool {
+		return strings.HasPrefix(a, op+"(")
+	})
+}
+
+func assertActions(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("actions:\n got  %q\n want %q", got, want)
+	}
+}
+
+// eventLog collects the events an App emits without a frontend.
+type eventLog struct {
+	mu     sync.Mutex
+	events []testEvent
+}
+
+type testEvent struct {
+	name    string
+	payload map[string]interface{}
+}
+
+// recordEvents makes app send its events to the returned log.
+func recordEvents(app *App) *eventLog {
+	l := &eventLog{}
+	app.onEvent = func(name string, payload interface{}) {
+		p, _ := payload.(map[string]interface{})
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.events = append(l.events, testEvent{name: name, payload: p})
+	}
+	return l
+}
+
+// named returns the events called name, in order.
+func (l *eventLog) named(name string) []testEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []testEvent
+	for _, e := range l.events {
+		if e.name == name {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// wait returns the events called name once there is at least one, or none
+// after a second. Events that follow the end of a run, such as how it ended,
+// can arrive after waitIdle returns.
+func (l *eventLog) wait(name string) []testEvent {
+	deadline := time.Now().Add(time.Second)
+	for len(l.named(name)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return l.named(name)
+}
@@ -0,0 +1,11 @@
+// hook_other.go
+
+//go:build !windows
+
+package main
+
+// newSystemHookSource returns the desktop HookSource, or nil where global
+// input hooks aren't implemented.
+func newSystemHookSource() HookSource {
+	return nil
+}
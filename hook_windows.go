@@ -0,0 +1,309 @@
+// hook_windows.go
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unsafe"
+)
+
+var (
+	user32                  = syscall.NewLazyDLL("user32.dll")
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procSetWindowsHookExW   = user32.NewProc("SetWindowsHookExW")
+	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
+	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
+	procGetMessageW         = user32.NewProc("GetMessageW")
+	procPostThreadMessageW  = user32.NewProc("PostThreadMessageW")
+	procGetKeyState         = user32.NewProc("GetKeyState")
+	procToUnicode           = user32.NewProc("ToUnicode")
+	procGetModuleHandleW    = kernel32.NewProc("GetModuleHandleW")
+	procGetCurrentThreadId  = kernel32.NewProc("GetCurrentThreadId")
+)
+
+const (
+	whKeyboardLL = 13
+	whMouseLL    = 14
+
+	wmQuit        = 0x0012
+	wmKeyDown     = 0x0100
+	wmKeyUp       = 0x0101
+	wmSysKeyDown  = 0x0104
+	wmSysKeyUp    = 0x0105
+	wmMouseMove   = 0x0200
+	wmLButtonDown = 0x0201
+	wmLButtonUp   = 0x0202
+	wmRButtonDown = 0x0204
+	wmRButtonUp   = 0x0205
+	wmMButtonDown = 0x0207
+	wmMButtonUp   = 0x0208
+	wmMouseWheel  = 0x020A
+	wmMouseHWheel = 0x020E
+
+	llkhfInjected = 0x10
+	llmhfInjected = 0x01
+	wheelDelta    = 120
+
+	vkShift   = 0x10
+	vkCapital = 0x14
+)
+
+type kbdllHookStruct struct {
+	VkCode      uint32
+	ScanCode    uint32
+	Flags       uint32
+	Time        uint32
+	DwExtraInfo uintptr
+}
+
+type msllHookStruct struct {
+	X, Y        int32
+	MouseData   uint32
+	Flags       uint32
+	Time        uint32
+	DwExtraInfo uintptr
+}
+
+type winMsg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	X, Y    int32
+}
+
+// windowsHookSource captures input with low-level keyboard and mouse hooks.
+// Windows calls a hook on the thread that installed it, which must run a
+// message loop, so each subscription gets a locked OS thread of its own.
+// Events injected by another program, including Keypress's own playback, are
+// ignored.
+type windowsHookSource struct{}
+
+// hookSubscription is one hook thread and the channel it delivers to.
+type hookSubscription struct {
+	threadID uintptr
+	events   chan InputEvent
+	shift    bool
+}
+
+// The hook procedures can't be freed, so they are created once and look up
+// the subscription by the thread they are called on.
+var (
+	subscriptionsMu  sync.Mutex
+	subscriptions    = make(map[uintptr]*hookSubscription)
+	keyboardCallback = syscall.NewCallback(keyboardHookProc)
+	mouseCallback    = syscall.NewCallback(mouseHookProc)
+)
+
+func newSystemHookSource() HookSource {
+	return windowsHookSource{}
+}
+
+func (windowsHookSource) Subscribe() (<-chan InputEvent, func(), error) {
+	s := &hookSubscription{events: make(chan InputEvent, 4096)}
+	started := make(chan error, 1)
+	go s.loop(started)
+	if err := <-started; err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			procPostThreadMessageW.Call(s.threadID, wmQuit, 0, 0)
+		})
+	}
+	return s.events, cancel, nil
+}
+
+// loop installs the hooks and pumps messages until cancel posts WM_QUIT.
+func (s *hookSubscription) loop(started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	s.threadID, _, _ = procGetCurrentThreadId.Call()
+
+	subscriptionsMu.Lock()
+	subscriptions[s.threadID] = s
+	subscriptionsMu.Unlock()
+	defer func() {
+		subscriptionsMu.Lock()
+		delete(subscriptions, s.threadID)
+		subscriptionsMu.Unlock()
+	}()
+
+	module, _, _ := procGetModuleHandleW.Call(0)
+	keyboard, _, err := procSetWindowsHookExW.Call(whKeyboardLL, keyboardCallback, module, 0)
+	if keyboard == 0 {
+		started <- fmt.Errorf("installing keyboard hook: %w", err)
+		return
+	}
+	defer procUnhookWindowsHookEx.Call(keyboard)
+	mouse, _, err := procSetWindowsHookExW.Call(whMouseLL, mouseCallback, module, 0)
+	if mouse == 0 {
+		started <- fmt.Errorf("installing mouse hook: %w", err)
+		return
+	}
+	defer procUnhookWindowsHookEx.Call(mouse)
+	started <- nil
+
+	var msg winMsg
+	for {
+		// Returns 0 for WM_QUIT and -1 on error
+		r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(r) <= 0 {
+			break
+		}
+	}
+	close(s.events)
+}
+
+// send delivers an event without ever blocking the hook, which Windows
+// would otherwise time out and remove.
+func (s *hookSubscription) send(e InputEvent) {
+	select {
+	case s.events <- e:
+	default:
+	}
+}
+
+// currentSubscription returns the subscription whose thread a hook is
+// running on.
+func currentSubscription() *hookSubscription {
+	threadID, _, _ := procGetCurrentThreadId.Call()
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	return subscriptions[threadID]
+}
+
+func keyboardHookProc(code int, wParam, lParam uintptr) uintptr {
+	if s := currentSubscription(); code >= 0 && s != nil {
+		k := *(**kbdllHookStruct)(unsafe.Pointer(&lParam))
+		if k.Flags&llkhfInjected == 0 {
+			s.keyboardEvent(uint32(wParam), k)
+		}
+	}
+	r, _, _ := procCallNextHookEx.Call(0, uintptr(code), wParam, lParam)
+	return r
+}
+
+func (s *hookSubscription) keyboardEvent(message uint32, k *kbdllHookStruct) {
+	key, ok := vkNames[k.VkCode]
+	if !ok {
+		return
+	}
+	e := InputEvent{Time: time.Now(), Key: key}
+	switch message {
+	case wmKeyDown, wmSysKeyDown:
+		e.Kind = EventKeyDown
+		if key == "shift" {
+			s.shift = true
+		}
+		e.Text = keyText(k, s.shift)
+	case wmKeyUp, wmSysKeyUp:
+		e.Kind = EventKeyUp
+		if key == "shift" {
+			s.shift = false
+		}
+	default:
+		return
+	}
+	s.send(e)
+}
+
+// keyText returns the printable text a key produces with the current
+// layout, or "".
+func keyText(k *kbdllHookStruct, shift bool) string {
+	var state [256]byte
+	if shift {
+		state[vkShift] = 0x80
+	}
+	if capsLock, _, _ := procGetKeyState.Call(vkCapital); capsLock&1 != 0 {
+		state[vkCapital] = 0x01
+	}
+	var buf [8]uint16
+	// Flag 4 leaves the keyboard state, including dead keys, untouched
+	n, _, _ := procToUnicode.Call(uintptr(k.VkCode), uintptr(k.ScanCode),
+		uintptr(unsafe.Pointer(&state[0])), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 4)
+	if int32(n) <= 0 {
+		return ""
+	}
+	text := string(utf16.Decode(buf[:n]))
+	for _, r := range text {
+		if !unicode.IsPrint(r) {
+			return ""
+		}
+	}
+	return text
+}
+
+func mouseHookProc(code int, wParam, lParam uintptr) uintptr {
+	if s := currentSubscription(); code >= 0 && s != nil {
+		m := *(**msllHookStruct)(unsafe.Pointer(&lParam))
+		if m.Flags&llmhfInjected == 0 {
+			s.mouseEvent(uint32(wParam), m)
+		}
+	}
+	r, _, _ := procCallNextHookEx.Call(0, uintptr(code), wParam, lParam)
+	return r
+}
+
+func (s *hookSubscription) mouseEvent(message uint32, m *msllHookStruct) {
+	e := InputEvent{Time: time.Now(), X: int(m.X), Y: int(m.Y)}
+	// The wheel delta is the signed high word, positive away from the user
+	delta := int(int16(m.MouseData>>16)) / wheelDelta
+	switch message {
+	case wmMouseMove:
+		e.Kind = EventMove
+	case wmLButtonDown, wmRButtonDown, wmMButtonDown:
+		e.Kind, e.Button = EventMouseDown, mouseButtons[message]
+	case wmLButtonUp, wmRButtonUp, wmMButtonUp:
+		e.Kind, e.Button = EventMouseUp, mouseButtons[message]
+	case wmMouseWheel:
+		e.Kind, e.ScrollY = EventScroll, -delta
+	case wmMouseHWheel:
+		e.Kind, e.ScrollX = EventScroll, delta
+	default:
+		return
+	}
+	s.send(e)
+}
+
+var mouseButtons = map[uint32]string{
+	wmLButtonDown: "left", wmLButtonUp: "left",
+	wmRButtonDown: "right", wmRButtonUp: "right",
+	wmMButtonDown: "middle", wmMButtonUp: "middle",
+}
+
+// vkNames maps virtual-key codes to robotgo key names.
+var vkNames = func() map[uint32]string {
+	names := map[uint32]string{
+		0x08: "backspace", 0x09: "tab", 0x0D: "enter", 0x1B: "esc", 0x20: "space",
+		0x21: "pageup", 0x22: "pagedown", 0x23: "end", 0x24: "home",
+		0x25: "left", 0x26: "up", 0x27: "right", 0x28: "down",
+		0x2C: "printscreen", 0x2D: "insert", 0x2E: "delete", 0x14: "capslock", 0x13: "pause",
+		0x10: "shift", 0xA0: "shift", 0xA1: "shift",
+		0x11: "ctrl", 0xA2: "ctrl", 0xA3: "ctrl",
+		0x12: "alt", 0xA4: "alt", 0xA5: "alt",
+		0x5B: "cmd", 0x5C: "cmd",
+		0x6A: "num*", 0x6B: "num+", 0x6D: "num-", 0x6E: "num.", 0x6F: "num/",
+		0xBA: ";", 0xBB: "=", 0xBC: ",", 0xBD: "-", 0xBE: ".", 0xBF: "/",
+		0xC0: "`", 0xDB: "[", 0xDC: "\\", 0xDD: "]", 0xDE: "'",
+	}
+	for c := uint32('0'); c <= '9'; c++ {
+		names[c] = string(rune(c))
+		names[0x60+c-'0'] = fmt.Sprintf("num%c", c)
+	}
+	for c := uint32('A'); c <= 'Z'; c++ {
+		names[c] = string(rune(c - 'A' + 'a'))
+	}
+	for i := uint32(0); i < 24; i++ {
+		names[0x70+i] = fmt.Sprintf("f%d", i+1)
+	}
+	return names
+}()
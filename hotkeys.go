@@ -0,0 +1,164 @@
+// hotkeys.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Hotkey actions.
+const (
+	hotkeyStart = "start"
+	hotkeyStop  = "stop"
+	hotkeyPause = "pause"
+)
+
+// hotkeySequenceGap is the longest pause between the chords of a sequence.
+const hotkeySequenceGap = 500 * time.Millisecond
+
+// hotkeyKeys are keys the hook source reports that robotgo can't press.
+var hotkeyKeys = []string{"pause"}
+
+// parseHotkey parses a hotkey written as a shortcut, such as "ctrl+alt+s"
+// or "esc esc".
+func parseHotkey(s string) ([]keyChord, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errors.New("empty hotkey")
+	}
+	chords, err := parseShortcut(s, hotkeyKeys...)
+	if err != nil {
+		return nil, err
+	}
+	// The listener treats modifiers, left or right, as held, never as pressed
+	for _, c := range chords {
+		if isModifier(strings.TrimLeft(c.Key, "lr")) {
+			return nil, fmt.Errorf("%q has no key besides modifiers", c.String())
+		}
+	}
+	return chords, nil
+}
+
+// hotkeyListener watches global key presses for hotkeys.
+type hotkeyListener struct {
+	cancel func()
+	done   chan struct{}
+}
+
+// startHotkeys listens to hooks and calls fire with the action of each
+// hotkey pressed. fire runs on its own goroutine.
+func startHotkeys(hooks HookSource, settings HotkeySettings, fire func(action string)) (*hotkeyListener, error) {
+	hotkeys := make(map[string][]keyChord)
+	for action, hotkey := range settings.bindings() {
+		chords, err := parseHotkey(hotkey)
+		if err != nil {
+			return nil, fmt.Errorf("hotkeys.%s: %w", action, err)
+		}
+		hotkeys[action] = chords
+	}
+
+	events, cancel, err := hooks.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	l := &hotkeyListener{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		l.listen(events, hotkeys, fire)
+	}()
+	return l, nil
+}
+
+func (l *hotkeyListener) listen(events <-chan InputEvent, hotkeys map[string][]keyChord, fire func(action string)) {
+	m := newHotkeyMatcher(hotkeys)
+	for e := range events {
+		for _, match := range m.feed(e) {
+			go fire(match.action)
+		}
+	}
+}
+
+// stop stops listening.
+func (l *hotkeyListener) stop() {
+	l.cancel()
+	<-l.done
+}
+
+//=============================================== Matching ===============================================
+
+// hotkeyMatcher follows key events through the chords of each hotkey. The
+// listener fires the hotkeys it completes, and the recorder leaves their
+// presses out.
+type hotkeyMatcher struct {
+	hotkeys  map[string][]keyChord
+	held     map[string]bool  // modifiers
+	down     map[string]bool  // other keys, to ignore auto-repeat
+	steps    map[string][]int // presses matching the first chords of each hotkey
+	lastStep map[string]time.Time
+	fed      int // events fed so far
+}
+
+// hotkeyMatch is a hotkey completed by the events fed to a hotkeyMatcher.
+type hotkeyMatch struct {
+	action  string
+	presses []int // positions of its chords' key presses among the events fed
+}
+
+func newHotkeyMatcher(hotkeys map[string][]keyChord) *hotkeyMatcher {
+	return &hotkeyMatcher{
+		hotkeys:  hotkeys,
+		held:     make(map[string]bool),
+		down:     make(map[string]bool),
+		steps:    make(map[string][]int),
+		lastStep: make(map[string]time.Time),
+	}
+}
+
+// feed follows the next event and returns the hotkeys it completes.
+func (m *hotkeyMatcher) feed(e InputEvent) []hotkeyMatch {
+	pos := m.fed
+	m.fed++
+
+	switch e.Kind {
+	case EventKeyDown:
+		if isModifier(e.Key) {
+			m.held[e.Key] = true
+			return nil
+		}
+		if m.down[e.Key] {
+			return nil
+		}
+		m.down[e.Key] = true
+
+		chord := keyChord{Key: e.Key, Modifiers: orderedModifiers(m.held)}
+		var matches []hotkeyMatch
+		for action, chords := range m.hotkeys {
+			steps := m.steps[action]
+			if len(steps) > 0 && e.Time.Sub(m.lastStep[action]) > hotkeySequenceGap {
+				steps = nil
+			}
+			switch {
+			case chords[len(steps)].equal(chord):
+				steps = append(steps, pos)
+			case chords[0].equal(chord):
+				steps = []int{pos}
+			default:
+				steps = nil
+			}
+			if len(steps) == len(chords) {
+				matches = append(matches, hotkeyMatch{action: action, presses: steps})
+				steps = nil
+			}
+			m.steps[action] = steps
+			m.lastStep[action] = e.Time
+		}
+		return matches
+
+	case EventKeyUp:
+		delete(m.held, e.Key)
+		delete(m.down, e.Key)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// hotkeys_test.go
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// listenHotkeys starts a listener for the default hotkeys on a
+// FakeHookSource and returns the actions fired, in order, once script has
+// been sent.
+func listenHotkeys(t *testing.T, s *inputScript) []string {
+	t.Helper()
+	hooks := NewFakeHookSource()
+	fired := make(chan string, 10)
+	l, err := startHotkeys(hooks, defaultSettings().Hotkeys, func(action string) { fired <- action })
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks.Send(s.events...)
+	l.stop()
+
+	// fire runs on its own goroutine, so give the last ones time to arrive
+	var actions []string
+	for {
+		select {
+		case action := <-fired:
+			actions = append(actions, action)
+		case <-time.After(50 * time.Millisecond):
+			return actions
+		}
+	}
+}
+
+func TestHotkeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		script func(s *inputScript)
+		want   []string
+	}{
+		{"chord", func(s *inputScript) { s.press(0, "s", "s", "ctrl", "alt") }, []string{hotkeyStart}},
+		{"extra modifier", func(s *inputScript) { s.press(0, "s", "s", "ctrl", "alt", "shift") }, nil},
+		{"missing modifier", func(s *inputScript) { s.press(0, "s", "s", "ctrl") }, nil},
+		{"key the hook reports", func(s *inputScript) { s.press(0, "pause", "") }, []string{hotkeyPause}},
+		{"sequence", func(s *inputScript) {
+			s.press(0, "esc", "")
+			s.press(300, "esc", "")
+		}, []string{hotkeyStop}},
+		{"sequence too slow", func(s *inputScript) {
+			s.press(0, "esc", "")
+			s.press(600, "esc", "")
+		}, nil},
+		{"sequence restarted", func(s *inputScript) {
+			s.press(0, "esc", "")
+			s.press(100, "a", "a")
+			s.press(200, "esc", "")
+			s.press(300, "esc", "")
+		}, []string{hotkeyStop}},
+		{"held key repeats once", func(s *inputScript) {
+			s.events = append(s.events,
+				InputEvent{Kind: EventKeyDown, Time: s.at(0), Key: "esc"},
+				InputEvent{Kind: EventKeyDown, Time: s.at(30), Key: "esc"},
+				InputEvent{Kind: EventKeyUp, Time: s.at(60), Key: "esc"})
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newInputScript()
+			tt.script(s)
+			assertActions(t, listenHotkeys(t, s), tt.want...)
+		})
+	}
+}
+
+func TestParseHotkey(t *testing.T) {
+	for _, ok := range []string{"ctrl+alt+s", "esc esc", "pause", "F5", "ctrl+shift+pause"} {
+		if _, err := parseHotkey(ok); err != nil {
+			t.Errorf("parseHotkey(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "  ", "ctrl", "ctrl+lshift", "ctrl+nope"} {
+		if _, err := parseHotkey(bad); err == nil {
+			t.Errorf("parseHotkey(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestStopHotkeyStopsRun(t *testing.T) {
+	hooks := NewFakeHookSource()
+	app := NewAppWithDriver(NewRecordingDriver())
+	app.hooks = hooks
+	events := recordEvents(app)
+	app.settingsMu.Lock()
+	err := app.applyHotkeys()
+	app.settingsMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.hotkeys.stop()
+
+	if _, err := app.StartExecution(chain(node("d", "DelayNode", `{"time":10000}`))); err != nil {
+		t.Fatal(err)
+	}
+	s := newInputScript()
+	s.press(0, "esc", "")
+	s.press(100, "esc", "")
+	hooks.Send(s.events...)
+	waitIdle(t, app)
+
+	if got := events.named("hotkey-pressed"); len(got) != 1 || got[0].payload["action"] != hotkeyStop {
+		t.Errorf("hotkey-pressed events: %+v", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(events.named("execution-stopped")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("run was not stopped")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSaveSettingsWithoutHooks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+	defer xdg.Reload()
+
+	app := NewAppWithDriver(NewRecordingDriver())
+	settings := defaultSettings()
+	settings.Hotkeys.Stop = "ctrl+q"
+	// The hotkeys are kept for platforms that have hooks
+	if err := app.SaveSettings(settings); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+	if got := app.GetSettings().Hotkeys.Stop; got != "ctrl+q" {
+		t.Errorf("stop hotkey is %q after saving", got)
+	}
+	if err := app.SaveSettings(Settings{Hotkeys: HotkeySettings{Stop: "ctrl"}}); err == nil {
+		t.Error("SaveSettings accepted an invalid hotkey")
+	}
+}
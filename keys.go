@@ -0,0 +1,344 @@
+// keys.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyNames are the names robotgo can press besides single characters.
+// robotgo silently presses nothing for any other name, so keys are checked
+// against these before a run.
+var keyNames = func() map[string]bool {
+	names := map[string]bool{}
+	for _, n := range []string{
+		"backspace", "delete", "enter", "tab", "esc", "space",
+		"up", "down", "left", "right", "home", "end", "pageup", "pagedown",
+		"insert", "printscreen", "capslock", "menu",
+		"ctrl", "lctrl", "rctrl", "alt", "lalt", "ralt",
+		"shift", "lshift", "rshift", "cmd", "lcmd", "rcmd",
+		"num_lock", "num.", "num+", "num-", "num*", "num/",
+		"num_clear", "num_enter", "num_equal",
+		"audio_mute", "audio_vol_down", "audio_vol_up", "audio_play",
+		"audio_stop", "audio_pause", "audio_prev", "audio_next",
+		"audio_rewind", "audio_forward", "audio_repeat", "audio_random",
+		"lights_mon_up", "lights_mon_down", "lights_kbd_toggle",
+		"lights_kbd_up", "lights_kbd_down",
+	} {
+		names[n] = true
+	}
+	for i := 1; i <= 24; i++ {
+		names[fmt.Sprintf("f%d", i)] = true
+	}
+	for c := '0'; c <= '9'; c++ {
+		names[fmt.Sprintf("num%c", c)] = true
+	}
+	return names
+}()
+
+// keyAliases maps alternative key names, including AutoHotkey's, to
+// robotgo's.
+var keyAliases = map[string]string{
+	"escape":           "esc",
+	"return":           "enter",
+	"del":              "delete",
+	"bs":               "backspace",
+	"ins":              "insert",
+	"pgup":             "pageup",
+	"pgdn":             "pagedown",
+	"print":            "printscreen",
+	"prtsc":            "printscreen",
+	"appskey":          "menu",
+	"numlock":          "num_lock",
+	"numpadenter":      "num_enter",
+	"numpaddot":        "num.",
+	"numpadadd":        "num+",
+	"numpadsub":        "num-",
+	"numpadmult":       "num*",
+	"numpaddiv":        "num/",
+	"lcontrol":         "lctrl",
+	"rcontrol":         "rctrl",
+	"lwin":             "lcmd",
+	"rwin":             "rcmd",
+	"volume_mute":      "audio_mute",
+	"volume_up":        "audio_vol_up",
+	"volume_down":      "audio_vol_down",
+	"media_play_pause": "audio_play",
+	"media_stop":       "audio_stop",
+	"media_next":       "audio_next",
+	"media_prev":       "audio_prev",
+}
+
+func init() {
+	for c := '0'; c <= '9'; c++ {
+		keyAliases[fmt.Sprintf("numpad%c", c)] = fmt.Sprintf("num%c", c)
+	}
+}
+
+// modifierAliases maps alternative modifier names to the ones the hook
+// source reports.
+var modifierAliases = map[string]string{
+	"control": "ctrl",
+	"option":  "alt",
+	"win":     "cmd",
+	"windows": "cmd",
+	"super":   "cmd",
+	"meta":    "cmd",
+	"command": "cmd",
+}
+
+// parseKey returns the robotgo name of a single key, resolving aliases.
+// Names in extra are accepted as they are.
+func parseKey(name string, extra ...string) (string, error) {
+	key := strings.ToLower(name)
+	if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+	if alias, ok := modifierAliases[key]; ok {
+		key = alias
+	}
+	if keyNames[key] || slices.Contains(extra, key) {
+		return key, nil
+	}
+	if len(key) == 1 && key[0] > ' ' && key[0] <= '~' {
+		return key, nil
+	}
+	if key == "" {
+		return "", errors.New("empty key name")
+	}
+	candidates := slices.Concat(mapKeys(keyNames), mapKeys(keyAliases), mapKeys(modifierAliases), extra)
+	return "", fmt.Errorf("unknown key %q%s", name, didYouMean(key, candidates))
+}
+
+// parseModifiers checks modifier names, resolving aliases, and returns them
+// in recordModifiers order without duplicates.
+func parseModifiers(names []string) ([]string, error) {
+	held := make(map[string]bool)
+	for _, name := range names {
+		m := strings.ToLower(name)
+		if alias, ok := modifierAliases[m]; ok {
+			m = alias
+		}
+		if !isModifier(m) {
+			candidates := slices.Concat(recordModifiers, mapKeys(modifierAliases))
+			return nil, fmt.Errorf("%q is not a modifier (use ctrl, alt, shift or cmd)%s", name, didYouMean(m, candidates))
+		}
+		held[m] = true
+	}
+	return orderedModifiers(held), nil
+}
+
+// orderedModifiers returns the modifiers in held in recordModifiers order.
+func orderedModifiers(held map[string]bool) []string {
+	var out []string
+	for _, m := range recordModifiers {
+		if held[m] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+//=============================================== Shortcuts ===============================================
+
+// keyChord is a key pressed while exactly Modifiers are held.
+type keyChord struct {
+	Key       string
+	Modifiers []string // in recordModifiers order
+}
+
+func (c keyChord) String() string {
+	return strings.Join(append(slices.Clone(c.Modifiers), c.Key), "+")
+}
+
+func (c keyChord) equal(o keyChord) bool {
+	return c.Key == o.Key && slices.Equal(c.Modifiers, o.Modifiers)
+}
+
+// parseShortcut parses chords such as "ctrl+shift+t" separated by spaces,
+// so "ctrl+k ctrl+c" is a sequence of two. "ctrl++" presses the + key.
+// Names in extra are accepted as keys besides robotgo's.
+func parseShortcut(s string, extra ...string) ([]keyChord, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, errors.New("empty shortcut")
+	}
+	chords := make([]keyChord, len(fields))
+	for i, field := range fields {
+		chord, err := parseChord(field, extra)
+		if err != nil {
+			return nil, err
+		}
+		chords[i] = chord
+	}
+	return chords, nil
+}
+
+func parseChord(field string, extra []string) (keyChord, error) {
+	// The key is after the last +, unless the key is + itself
+	split := strings.LastIndex(field[:len(field)-1], "+")
+	name := field[split+1:]
+	var modifiers []string
+	if split >= 0 {
+		modifiers = strings.Split(field[:split], "+")
+	}
+
+	key, err := parseKey(name, extra...)
+	if err != nil && strings.HasSuffix(field, "+") {
+		return keyChord{}, fmt.Errorf("%q has no key after the last +", field)
+	}
+	if err != nil {
+		return keyChord{}, err
+	}
+	held, err := parseModifiers(modifiers)
+	if err != nil {
+		return keyChord{}, err
+	}
+	return keyChord{Key: key, Modifiers: held}, nil
+}
+
+//=============================================== Key Text ===============================================
+
+// keyStep is one step of typing text with keys: either Text to type, or
+// Chords to tap Repeat times.
+type keyStep struct {
+	Text   string
+	Chords []keyChord
+	Repeat int
+}
+
+// parseKeyText splits text written AutoHotkey-style into steps. Keys go in
+// braces, optionally with a repeat count: "Hi{Enter}{Tab 3}{ctrl+s}".
+// {{} and {}} type the braces themselves.
+func parseKeyText(text string) ([]keyStep, error) {
+	var steps []keyStep
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			steps = append(steps, keyStep{Text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "{{}"), strings.HasPrefix(text[i:], "{}}"):
+			literal.WriteByte(text[i+1])
+			i += 3
+		case text[i] == '}':
+			return nil, fmt.Errorf("} at %d has no matching { (write {}} to type it)", i)
+		case text[i] == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("{ at %d is never closed (write {{} to type it)", i)
+			}
+			step, err := parseKeyBraces(text[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", text[i:i+end+1], err)
+			}
+			flush()
+			steps = append(steps, step)
+			i += end + 1
+		default:
+			literal.WriteByte(text[i])
+			i++
+		}
+	}
+	flush()
+	return steps, nil
+}
+
+// parseKeyBraces parses what is between the braces: a shortcut and an
+// optional repeat count.
+func parseKeyBraces(s string) (keyStep, error) {
+	fields := strings.Fields(s)
+	step := keyStep{Repeat: 1}
+	if len(fields) > 1 {
+		if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			if n < 0 {
+				return keyStep{}, fmt.Errorf("repeat count %d is negative", n)
+			}
+			step.Repeat = n
+			fields = fields[:len(fields)-1]
+		}
+	}
+	if len(fields) == 0 {
+		return keyStep{}, errors.New("no key")
+	}
+	chords, err := parseShortcut(strings.Join(fields, " "))
+	step.Chords = chords
+	return step, err
+}
+
+//=============================================== Suggestions ===============================================
+
+// didYouMean suggests the names closest to a misspelled one, or returns ""
+// if none are close.
+func didYouMean(name string, candidates []string) string {
+	best := -1
+	var nearest []string
+	for _, c := range candidates {
+		d := editDistance(name, c)
+		if d > 2 || d > 1 && len(name) <= 4 {
+			continue
+		}
+		switch {
+		case best < 0 || d < best:
+			best, nearest = d, []string{c}
+		case d == best && !slices.Contains(nearest, c):
+			nearest = append(nearest, c)
+		}
+	}
+	if len(nearest) == 0 {
+		return ""
+	}
+	sort.Strings(nearest)
+	if len(nearest) > 3 {
+		nearest = nearest[:3]
+	}
+	quoted := make([]string, len(nearest))
+	for i, c := range nearest {
+		quoted[i] = strconv.Quote(c)
+	}
+	return fmt.Sprintf(" (did you mean %s?)", strings.Join(quoted, " or "))
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+// mapKeys returns the keys of m.
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,30 @@
+// keystate.go
+
+package main
+
+import "strings"
+
+// KeyStateReader reports whether a key is held down on the keyboard,
+// including by the user. Drivers that can read it implement it alongside
+// InputDriver; robotgo's does on Windows.
+type KeyStateReader interface {
+	IsKeyDown(key string) (bool, error)
+}
+
+// SetKeyDown sets whether IsKeyDown reports key as held, as if the user
+// held it, without recording an action.
+func (d *RecordingDriver) SetKeyDown(key string, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if down {
+		d.keysDown[strings.ToLower(key)] = true
+	} else {
+		delete(d.keysDown, strings.ToLower(key))
+	}
+}
+
+func (d *RecordingDriver) IsKeyDown(key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.keysDown[strings.ToLower(key)], nil
+}
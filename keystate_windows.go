@@ -0,0 +1,39 @@
+// keystate_windows.go
+
+package main
+
+import "fmt"
+
+var procGetAsyncKeyState = user32.NewProc("GetAsyncKeyState")
+
+// sidedModifierVKs are the virtual-key codes of modifiers of one side,
+// which vkNames reports without the side.
+var sidedModifierVKs = map[string]uint32{
+	"lshift": 0xA0, "rshift": 0xA1,
+	"lctrl": 0xA2, "rctrl": 0xA3,
+	"lalt": 0xA4, "ralt": 0xA5,
+	"lcmd": 0x5B, "rcmd": 0x5C,
+}
+
+// IsKeyDown reads the physical state of key, as robotgo names it.
+func (robotgoDriver) IsKeyDown(key string) (bool, error) {
+	var codes []uint32
+	if vk, ok := sidedModifierVKs[key]; ok {
+		codes = append(codes, vk)
+	}
+	for vk, name := range vkNames {
+		if name == key {
+			codes = append(codes, vk)
+		}
+	}
+	if len(codes) == 0 {
+		return false, fmt.Errorf("can't read whether %q is held down", key)
+	}
+	for _, vk := range codes {
+		// The high bit is set while the key is down
+		if state, _, _ := procGetAsyncKeyState.Call(uintptr(vk)); state&0x8000 != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,235 @@
+// loop.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func init() {
+	RegisterExecutor("LoopNode", loopExecutor{})
+}
+
+// LoopNode output handles. The body handle leads to the nodes repeated on
+// every iteration; the exit handle runs once the loop is done.
+const (
+	HandleLoopBody = "body"
+	HandleLoopExit = "exit"
+)
+
+// LoopData is the data of a LoopNode.
+type LoopData struct {
+	Mode          string        `json:"mode"` // "count", "while", "until" or "forEach"
+	Count         int           `json:"count"`
+	Condition     Condition     `json:"condition"`
+	MaxIterations int           `json:"maxIterations"` // guards while and until loops
+	Items         []interface{} `json:"items"`
+	ItemsVariable string        `json:"itemsVariable"` // variable holding the list, instead of items
+	ItemVariable  string        `json:"itemVariable"`  // variable set to the current item
+}
+
+func decodeLoopData(data map[string]interface{}) (LoopData, error) {
+	r := newDataReader(data)
+	d := LoopData{
+		Mode:          r.OneOf("mode", "count", "count", "while", "until", "forEach"),
+		Count:         r.Int("count", 1),
+		MaxIterations: r.Int("maxIterations", 1000),
+		Items:         r.List("items", nil),
+		ItemsVariable: r.String("itemsVariable", ""),
+		ItemVariable:  r.String("itemVariable", "item"),
+	}
+	r.Min("count", float64(d.Count), 0)
+	r.Min("maxIterations", float64(d.MaxIterations), 1)
+
+	switch d.Mode {
+	case "while", "until":
+		d.Condition = readCondition(r.Object("condition"))
+	case "forEach":
+		if d.ItemsVariable == "" && !r.Has("items") {
+			r.Fail("items", "array", nil, "set items or itemsVariable")
+		}
+		if d.ItemVariable == "" {
+			r.Fail("itemVariable", "non-empty string", d.ItemVariable, "")
+		}
+	}
+	return d, r.Err()
+}
+
+//=============================================== Executor ===============================================
+
+// loopExecutor decides, each time the LoopNode runs, whether to start another
+// iteration of the body or leave through the exit handle. The scheduler runs
+// the node again once every node of the body has finished.
+type loopExecutor struct{}
+
+func (loopExecutor) Validate(node Node) error {
+	_, err := decodeLoopData(node.Data)
+	return err
+}
+
+func (loopExecutor) Handles(node Node) []string {
+	return []string{HandleLoopBody, HandleLoopExit}
+}
+
+func (loopExecutor) Assigns(node Node) []string {
+	d, _ := decodeLoopData(node.Data)
+	if d.Mode != "forEach" {
+		return nil
+	}
+	return []string{d.ItemVariable}
+}
+
+func (loopExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	return decodeLoopData(ec.Task.Data)
+}
+
+func (loopExecutor) Describe(node Node) string {
+	d, err := decodeLoopData(node.Data)
+	if err != nil {
+		return "Repeat the loop body"
+	}
+	switch d.Mode {
+	case "while":
+		return fmt.Sprintf("Repeat while %s", d.Condition)
+	case "until":
+		return fmt.Sprintf("Repeat until %s", d.Condition)
+	case "forEach":
+		if d.ItemsVariable != "" {
+			return fmt.Sprintf("Repeat for each item in %s", d.ItemsVariable)
+		}
+		return fmt.Sprintf("Repeat for each of %d item(s)", len(d.Items))
+	default:
+		return fmt.Sprintf("Repeat %d time(s)", d.Count)
+	}
+}
+
+func (loopExecutor) Execute(ec *ExecContext) error {
+	d, err := decodeLoopData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	state := ec.State()
+	iteration, _ := state["iteration"].(int)
+
+	// The list is read once, when the loop is entered
+	var items []interface{}
+	if d.Mode == "forEach" {
+		if iteration == 0 {
+			if state["items"], err = loopItems(ec, d); err != nil {
+				return err
+			}
+		}
+		items, _ = state["items"].([]interface{})
+	}
+
+	more, err := loopContinues(ec, d, iteration, len(items))
+	if err != nil {
+		return err
+	}
+	if !more {
+		log.Printf("Loop %s finished after %d iteration(s)", ec.Task.ID, iteration)
+		ec.Emit("loop-completed", map[string]interface{}{"iterations": iteration})
+		// Start from scratch if the loop is entered again
+		for k := range state {
+			delete(state, k)
+		}
+		ec.Follow(HandleLoopExit)
+		return nil
+	}
+
+	iteration++
+	state["iteration"] = iteration
+	payload := map[string]interface{}{"iteration": iteration}
+	switch d.Mode {
+	case "count":
+		payload["total"] = d.Count
+	case "forEach":
+		item := items[iteration-1]
+		ec.SetVariable(d.ItemVariable, item)
+		payload["item"] = item
+		payload["total"] = len(items)
+	}
+	log.Printf("Loop %s starting iteration %d", ec.Task.ID, iteration)
+	ec.Emit("loop-iteration", payload)
+	ec.Follow(HandleLoopBody)
+	return nil
+}
+
+// loopItems returns the list a forEach loop iterates over.
+func loopItems(ec *ExecContext, d LoopData) ([]interface{}, error) {
+	if d.ItemsVariable == "" {
+		return d.Items, nil
+	}
+	v, ok := ec.Variable(d.ItemsVariable)
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set", d.ItemsVariable)
+	}
+	switch list := v.(type) {
+	case []interface{}:
+		return list, nil
+	case []string:
+		items := make([]interface{}, len(list))
+		for i, s := range list {
+			items[i] = s
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("variable %q is a %s, not a list", d.ItemsVariable, jsonTypeName(v))
+}
+
+// loopContinues reports whether another iteration should start after
+// iteration iterations have run. While loops test their condition before
+// every iteration; until loops always run the body once and test after it.
+func loopContinues(ec *ExecContext, d LoopData, iteration, items int) (bool, error) {
+	switch d.Mode {
+	case "count":
+		return iteration < d.Count, nil
+	case "forEach":
+		return iteration < items, nil
+	}
+
+	more := true
+	if d.Mode == "while" || iteration > 0 {
+		met, err := d.Condition.Evaluate(ec)
+		if err != nil {
+			return false, err
+		}
+		more = met == (d.Mode == "while")
+	}
+	if more && iteration >= d.MaxIterations {
+		return false, fmt.Errorf("loop stopped after %d iterations (maxIterations) without its condition being met", iteration)
+	}
+	return more, nil
+}
+
+//=============================================== Structure ===============================================
+
+// loopBranch returns the nodes reachable from the edges leaving loopID on
+// handle, without passing back through the loop itself. For the body handle
+// this is the set of nodes repeated on every iteration.
+func loopBranch(loopID, handle string, edges []Edge) map[string]bool {
+	adjacency := make(map[string][]string)
+	var stack []string
+	for _, edge := range edges {
+		if edge.Source == loopID {
+			if edge.SourceHandle == handle {
+				stack = append(stack, edge.Target)
+			}
+			continue
+		}
+		adjacency[edge.Source] = append(adjacency[edge.Source], edge.Target)
+	}
+
+	branch := make(map[string]bool)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == loopID || branch[id] {
+			continue
+		}
+		branch[id] = true
+		stack = append(stack, adjacency[id]...)
+	}
+	return branch
+}
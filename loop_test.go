@@ -0,0 +1,115 @@
+// loop_test.go
+
+package main
+
+import "testing"
+
+func TestLoopNode(t *testing.T) {
+	// Each loop types its body and taps enter on exit
+	loop := func(data string, body ...testNode) string {
+		nodes := append([]testNode{
+			node("set", "SetVariableNode", `{"name":"n","value":0}`),
+			node("loop", "LoopNode", data),
+			node("done", "KeyTap", `{"key":"enter"}`),
+		}, body...)
+		edges := []string{"start->set", "set->loop", "loop:exit->done", "loop:body->" + body[0].id}
+		for i := 1; i < len(body); i++ {
+			edges = append(edges, body[i-1].id+"->"+body[i].id)
+		}
+		return graph(nodes, edges...)
+	}
+	increment := node("inc", "SetVariableNode", `{"name":"n","operation":"increment"}`)
+	typeN := node("type", "TypeString", `{"text":"{{n}}"}`)
+
+	tests := []struct {
+		name string
+		flow string
+		want []string
+	}{
+		{
+			name: "count",
+			flow: loop(`{"mode":"count","count":3}`, node("type", "TypeString", `{"text":"{{iteration}}"}`)),
+			want: []string{"type(1)", "type(2)", "type(3)", "keyTap(enter)"},
+		},
+		{
+			name: "count of zero",
+			flow: loop(`{"mode":"count","count":0}`, typeN),
+			want: []string{"keyTap(enter)"},
+		},
+		{
+			name: "while",
+			flow: loop(`{"mode":"while","condition":{"variable":"n","operator":"<","value":3}}`, increment, typeN),
+			want: []string{"type(1)", "type(2)", "type(3)", "keyTap(enter)"},
+		},
+		{
+			name: "while false at first",
+			flow: loop(`{"mode":"while","condition":{"variable":"n","operator":">","value":0}}`, increment, typeN),
+			want: []string{"keyTap(enter)"},
+		},
+		{
+			name: "until runs once",
+			flow: loop(`{"mode":"until","condition":{"variable":"n","operator":">=","value":0}}`, increment, typeN),
+			want: []string{"type(1)", "keyTap(enter)"},
+		},
+		{
+			name: "forEach",
+			flow: loop(`{"mode":"forEach","items":["a","b"],"itemVariable":"letter"}`,
+				node("type", "TypeString", `{"text":"{{letter}}{{iteration}}"}`)),
+			want: []string{"type(a1)", "type(b2)", "keyTap(enter)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertActions(t, runFlow(t, tt.flow), tt.want...)
+		})
+	}
+}
+
+func TestNestedLoops(t *testing.T) {
+	flow := graph([]testNode{
+		node("outer", "LoopNode", `{"mode":"forEach","items":["x","y"],"itemVariable":"row"}`),
+		node("inner", "LoopNode", `{"mode":"count","count":2}`),
+		node("cell", "TypeString", `{"text":"{{row}}{{iteration}}"}`),
+		node("row-end", "KeyTap", `{"key":"tab"}`),
+		node("done", "KeyTap", `{"key":"enter"}`),
+	}, "start->outer", "outer:body->inner", "inner:body->cell", "inner:exit->row-end", "outer:exit->done")
+
+	assertActions(t, runFlow(t, flow),
+		"type(x1)", "type(x2)", "keyTap(tab)",
+		"type(y1)", "type(y2)", "keyTap(tab)",
+		"keyTap(enter)")
+}
+
+func TestLoopEarlyExit(t *testing.T) {
+	// The body stops the loop on its second iteration, long before maxIterations
+	flow := graph([]testNode{
+		node("stop", "SetVariableNode", `{"name":"stop","value":0}`),
+		node("n", "SetVariableNode", `{"name":"n","value":0}`),
+		node("loop", "LoopNode", `{"mode":"while","maxIterations":10,"condition":{"variable":"stop","operator":"==","value":0}}`),
+		node("inc", "SetVariableNode", `{"name":"n","operation":"increment"}`),
+		node("type", "TypeString", `{"text":"{{n}}"}`),
+		node("if", "IfNode", `{"condition":{"variable":"n","operator":">=","value":2}}`),
+		node("break", "SetVariableNode", `{"name":"stop","value":1}`),
+		node("done", "KeyTap", `{"key":"enter"}`),
+	}, "start->stop", "stop->n", "n->loop", "loop:body->inc", "inc->type", "type->if", "if:true->break", "loop:exit->done")
+
+	assertActions(t, runFlow(t, flow), "type(1)", "type(2)", "keyTap(enter)")
+}
+
+func TestLoopMaxIterations(t *testing.T) {
+	flow := graph([]testNode{
+		node("loop", "LoopNode", `{"mode":"while","maxIterations":3,"condition":{"type":"pixel","x":0,"y":0,"color":"000000"}}`),
+		node("type", "TypeString", `{"text":"{{iteration}}"}`),
+		node("done", "KeyTap", `{"key":"enter"}`),
+	}, "start->loop", "loop:body->type", "loop:exit->done")
+
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, flow)
+
+	assertActions(t, driver.ActionStrings(), "type(1)", "type(2)", "type(3)")
+	if failed := events.named("task-error"); len(failed) != 1 || failed[0].payload["taskID"] != "loop" {
+		t.Errorf("task-error events: %+v", failed)
+	}
+}
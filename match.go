@@ -0,0 +1,203 @@
+// match.go
+
+package main
+
+import (
+	"image"
+	"sort"
+)
+
+// imageMatch is where a template was found, in the searched image's
+// coordinates, with a similarity score from 0 (opposite) to 1 (identical).
+type imageMatch struct {
+	X, Y  int // top-left corner
+	Score float64
+	sad   int
+}
+
+// rgbImage is a compact copy of an image, 3 bytes per pixel, that the
+// matcher can scan quickly.
+type rgbImage struct {
+	w, h int
+	pix  []uint8
+}
+
+func toRGBImage(img image.Image) *rgbImage {
+	b := img.Bounds()
+	m := &rgbImage{w: b.Dx(), h: b.Dy(), pix: make([]uint8, 3*b.Dx()*b.Dy())}
+	i := 0
+	// Screen captures are RGBA; copy them without going through At
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := rgba.Pix[rgba.PixOffset(b.Min.X, y):]
+			for x := 0; x < m.w; x++ {
+				copy(m.pix[i:i+3], row[x*4:x*4+3])
+				i += 3
+			}
+		}
+		return m
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			m.pix[i], m.pix[i+1], m.pix[i+2] = uint8(r>>8), uint8(g>>8), uint8(bl>>8)
+			i += 3
+		}
+	}
+	return m
+}
+
+// downscale shrinks the image fx times horizontally and fy times
+// vertically, averaging each fx×fy block.
+func (m *rgbImage) downscale(fx, fy int) *rgbImage {
+	out := &rgbImage{w: m.w / fx, h: m.h / fy}
+	out.pix = make([]uint8, 3*out.w*out.h)
+	n := fx * fy
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			var sum [3]int
+			for dy := 0; dy < fy; dy++ {
+				row := ((y*fy+dy)*m.w + x*fx) * 3
+				for dx := 0; dx < fx*3; dx += 3 {
+					sum[0] += int(m.pix[row+dx])
+					sum[1] += int(m.pix[row+dx+1])
+					sum[2] += int(m.pix[row+dx+2])
+				}
+			}
+			o := (y*out.w + x) * 3
+			out.pix[o], out.pix[o+1], out.pix[o+2] = uint8(sum[0]/n), uint8(sum[1]/n), uint8(sum[2]/n)
+		}
+	}
+	return out
+}
+
+// sadChunk is how many bytes sad compares between checks of the limit.
+const sadChunk = 96
+
+// sad is the sum of absolute differences between tmpl and the area of m at
+// x, y. It gives up and returns a value above limit as soon as the sum
+// exceeds it.
+func (m *rgbImage) sad(tmpl *rgbImage, x, y, limit int) int {
+	total := 0
+	rowLen := tmpl.w * 3
+	for ty := 0; ty < tmpl.h; ty++ {
+		mRow := m.pix[((y+ty)*m.w+x)*3:]
+		tRow := tmpl.pix[ty*rowLen : (ty+1)*rowLen]
+		for len(tRow) > 0 {
+			n := min(len(tRow), sadChunk)
+			for i, tv := range tRow[:n] {
+				d := int(mRow[i]) - int(tv)
+				if d < 0 {
+					d = -d
+				}
+				total += d
+			}
+			if total > limit {
+				return total
+			}
+			mRow, tRow = mRow[n:], tRow[n:]
+		}
+	}
+	return total
+}
+
+// maxSAD is the largest possible difference for a template.
+func (m *rgbImage) maxSAD() int {
+	return 255 * len(m.pix)
+}
+
+// topMatches scans every position with its top-left corner inside
+// [x0, x1]×[y0, y1] and returns up to n best positions, best first. Only
+// positions differing by less than limit are considered.
+func (m *rgbImage) topMatches(tmpl *rgbImage, x0, y0, x1, y1, n, limit int) []imageMatch {
+	x1 = min(x1, m.w-tmpl.w)
+	y1 = min(y1, m.h-tmpl.h)
+	x0, y0 = max(x0, 0), max(y0, 0)
+
+	type scored struct {
+		x, y, sad int
+	}
+	var best []scored
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			d := m.sad(tmpl, x, y, limit)
+			if d >= limit || (len(best) == n && d >= best[n-1].sad) {
+				continue
+			}
+			i := sort.Search(len(best), func(i int) bool { return best[i].sad > d })
+			best = append(best, scored{})
+			copy(best[i+1:], best[i:])
+			best[i] = scored{x, y, d}
+			if len(best) > n {
+				best = best[:n]
+			}
+			// Once the list is full, nothing worse than its last entry matters
+			if len(best) == n {
+				limit = best[n-1].sad
+			}
+		}
+	}
+
+	out := make([]imageMatch, len(best))
+	for i, b := range best {
+		out[i] = imageMatch{X: b.x, Y: b.y, Score: 1 - float64(b.sad)/float64(tmpl.maxSAD()), sad: b.sad}
+	}
+	return out
+}
+
+// matchCandidates is how many positions found on the downscaled copies are
+// refined at full resolution.
+const matchCandidates = 8
+
+// matchFactor is how many times a side of size pixels of a template can be
+// shrunk, down to 8 pixels, while searching for it.
+func matchFactor(size int) int {
+	factor := 1
+	for factor < 8 && size/(factor*2) >= 8 {
+		factor *= 2
+	}
+	return factor
+}
+
+// findTemplate returns the best match of tmpl in img. Large templates are
+// first located on downscaled copies, then refined at full resolution
+// within a block of the best few candidates. A match rarely lines up with
+// the blocks, which blurs it on the copies, so more than one candidate is
+// kept. Searching a full HD screen takes from about 20 ms for large
+// templates to 200 ms for ones under 16 pixels a side, which can't be
+// downscaled.
+func findTemplate(img, tmpl image.Image) (imageMatch, bool) {
+	s, t := toRGBImage(img), toRGBImage(tmpl)
+	if t.w == 0 || t.h == 0 || t.w > s.w || t.h > s.h {
+		return imageMatch{}, false
+	}
+
+	fx, fy := matchFactor(t.w), matchFactor(t.h)
+
+	// Anything matches better than the largest possible difference
+	best := imageMatch{sad: t.maxSAD() + 1}
+	if fx == 1 && fy == 1 {
+		if found := s.topMatches(t, 0, 0, s.w, s.h, 1, best.sad); len(found) > 0 {
+			best = found[0]
+		}
+	} else {
+		small := s.downscale(fx, fy)
+		smallT := t.downscale(fx, fy)
+		// Candidates come best first, so the limit soon rules out most of
+		// the others' windows
+		for _, c := range small.topMatches(smallT, 0, 0, small.w, small.h, matchCandidates, smallT.maxSAD()+1) {
+			x, y := c.X*fx, c.Y*fy
+			if found := s.topMatches(t, x-fx, y-fy, x+fx, y+fy, 1, best.sad); len(found) > 0 {
+				best = found[0]
+			}
+		}
+	}
+	if best.sad > t.maxSAD() {
+		return imageMatch{}, false
+	}
+
+	b := img.Bounds()
+	best.X += b.Min.X
+	best.Y += b.Min.Y
+	return best, true
+}
@@ -0,0 +1,107 @@
+// match_test.go
+
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadPNG(t testing.TB, name string) image.Image {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestFindTemplate(t *testing.T) {
+	screen := loadPNG(t, "screen.png")
+	// Every position is off the grid of the downscaled blocks
+	tests := []struct {
+		tmpl string
+		x, y int
+	}{
+		{"tiny.png", 617, 95},   // searched at full resolution
+		{"icon.png", 609, 87},   // downscaled by 4
+		{"text.png", 317, 121},  // downscaled by 8, among similar text
+		{"patch.png", 531, 397}, // downscaled by 8, noise
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			m, ok := findTemplate(screen, loadPNG(t, tt.tmpl))
+			if !ok || m.X != tt.x || m.Y != tt.y || m.Score != 1 {
+				t.Errorf("got %+v, %v, want (%d, %d) with score 1", m, ok, tt.x, tt.y)
+			}
+		})
+	}
+
+	t.Run("absent", func(t *testing.T) {
+		m, ok := findTemplate(screen, loadPNG(t, "absent.png"))
+		if ok && m.Score >= 0.9 {
+			t.Errorf("found an absent image: %+v", m)
+		}
+	})
+
+	sub := func(img image.Image, r image.Rectangle) image.Image {
+		return img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}).SubImage(r)
+	}
+
+	t.Run("wide", func(t *testing.T) {
+		// Only shrunk horizontally, as it is too short to shrink vertically
+		m, ok := findTemplate(screen, sub(screen, image.Rect(323, 141, 523, 150)))
+		if !ok || m.X != 323 || m.Y != 141 || m.Score != 1 {
+			t.Errorf("got %+v, %v, want (323, 141) with score 1", m, ok)
+		}
+	})
+
+	t.Run("region", func(t *testing.T) {
+		// Captured regions keep their screen coordinates
+		m, ok := findTemplate(sub(screen, image.Rect(600, 80, 700, 150)), loadPNG(t, "icon.png"))
+		if !ok || m.X != 609 || m.Y != 87 {
+			t.Errorf("got %+v, %v, want (609, 87)", m, ok)
+		}
+	})
+
+	t.Run("larger than image", func(t *testing.T) {
+		if _, ok := findTemplate(loadPNG(t, "tiny.png"), screen); ok {
+			t.Error("found a template larger than the image")
+		}
+	})
+}
+
+// fullHDScreen tiles the fixture screen over 1920×1080.
+func fullHDScreen(t testing.TB) *image.RGBA {
+	tile := loadPNG(t, "screen.png")
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := 0; y < 1080; y += tile.Bounds().Dy() {
+		for x := 0; x < 1920; x += tile.Bounds().Dx() {
+			draw.Draw(img, image.Rect(x, y, 1920, 1080), tile, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+func BenchmarkFindTemplate(b *testing.B) {
+	screen := fullHDScreen(b)
+	for _, name := range []string{"tiny.png", "icon.png", "text.png", "patch.png"} {
+		tmpl := loadPNG(b, name)
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				findTemplate(screen, tmpl)
+			}
+		})
+	}
+}
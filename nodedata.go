@@ -0,0 +1,542 @@
+// nodedata.go
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single invalid field in a node's data.
+type FieldError struct {
+	Path     string      `json:"path"`
+	Expected string      `json:"expected"`
+	Actual   interface{} `json:"actual"`
+	Message  string      `json:"message,omitempty"`
+}
+
+func (e FieldError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	if e.Actual == nil {
+		return fmt.Sprintf("%s: expected %s, got nothing", e.Path, e.Expected)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s %#v", e.Path, e.Expected, jsonTypeName(e.Actual), e.Actual)
+}
+
+// FieldErrors is every problem found while decoding a node's data.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// jsonTypeName names the JSON type of a decoded value.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}, []string:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// dataReader decodes loosely typed node data. Missing or null fields fall
+// back to a default, while present fields of the wrong type are recorded as
+// FieldErrors so every problem is reported at once instead of panicking on
+// the first bad type assertion.
+type dataReader struct {
+	prefix    string
+	data      map[string]interface{}
+	errs      *FieldErrors
+	templates bool
+}
+
+func newDataReader(data map[string]interface{}) *dataReader {
+	return &dataReader{data: data, errs: &FieldErrors{}}
+}
+
+// AllowTemplates lets number fields hold strings: numeric strings are parsed,
+// and strings with {{name}} templates are accepted before a run, when their
+// value isn't known yet.
+func (r *dataReader) AllowTemplates() *dataReader {
+	r.templates = true
+	return r
+}
+
+// path returns the dotted path of key relative to the node's data.
+func (r *dataReader) path(key string) string {
+	if r.prefix == "" {
+		return key
+	}
+	return r.prefix + "." + key
+}
+
+// Fail records an error against key.
+func (r *dataReader) Fail(key, expected string, actual interface{}, message string) {
+	*r.errs = append(*r.errs, FieldError{Path: r.path(key), Expected: expected, Actual: actual, Message: message})
+}
+
+// Err returns the collected errors, or nil if there were none.
+func (r *dataReader) Err() error {
+	if len(*r.errs) == 0 {
+		return nil
+	}
+	return *r.errs
+}
+
+func (r *dataReader) lookup(key string) (interface{}, bool) {
+	v, ok := r.data[key]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// Has reports whether key is present and not null.
+func (r *dataReader) Has(key string) bool {
+	_, ok := r.lookup(key)
+	return ok
+}
+
+// Require records an error if key is missing.
+func (r *dataReader) Require(key, expected string) bool {
+	if r.Has(key) {
+		return true
+	}
+	r.Fail(key, expected, nil, "")
+	return false
+}
+
+func (r *dataReader) Float(key string, def float64) float64 {
+	v, ok := r.lookup(key)
+	if !ok {
+		return def
+	}
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case string:
+		if r.templates {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+				return f
+			}
+			if hasTemplate(n) {
+				return def
+			}
+		}
+	}
+	r.Fail(key, "number", v, "")
+	return def
+}
+
+func (r *dataReader) Int(key string, def int) int {
+	if !r.Has(key) {
+		return def
+	}
+	f := r.Float(key, math.NaN())
+	if math.IsNaN(f) {
+		return def
+	}
+	if f != math.Trunc(f) {
+		r.Fail(key, "integer", f, "")
+		return def
+	}
+	return int(f)
+}
+
+func (r *dataReader) String(key, def string) string {
+	v, ok := r.lookup(key)
+	if !ok {
+		return def
+	}
+	s, ok := v.(string)
+	if !ok {
+		r.Fail(key, "string", v, "")
+		return def
+	}
+	return s
+}
+
+func (r *dataReader) Bool(key string, def bool) bool {
+	v, ok := r.lookup(key)
+	if !ok {
+		return def
+	}
+	b, ok := v.(bool)
+	if !ok {
+		r.Fail(key, "boolean", v, "")
+		return def
+	}
+	return b
+}
+
+// OneOf reads a string that must be one of allowed.
+func (r *dataReader) OneOf(key, def string, allowed ...string) string {
+	s := r.String(key, def)
+	for _, a := range allowed {
+		if s == a {
+			return s
+		}
+	}
+	r.Fail(key, "one of "+strings.Join(allowed, ", "), s, "")
+	return def
+}
+
+// Strings reads an array of strings.
+func (r *dataReader) Strings(key string, def []string) []string {
+	v, ok := r.lookup(key)
+	if !ok {
+		return def
+	}
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				r.Fail(fmt.Sprintf("%s[%d]", key, i), "string", item, "")
+				continue
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	r.Fail(key, "array", v, "")
+	return def
+}
+
+// Value returns a field of any JSON type, or nil if it is missing.
+func (r *dataReader) Value(key string) interface{} {
+	v, _ := r.lookup(key)
+	return v
+}
+
+// List reads an array of values of any type.
+func (r *dataReader) List(key string, def []interface{}) []interface{} {
+	v, ok := r.lookup(key)
+	if !ok {
+		return def
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		r.Fail(key, "array", v, "")
+		return def
+	}
+	return list
+}
+
+// Object returns a reader for a nested object. A missing object yields an
+// empty reader, so all of its fields take their defaults.
+func (r *dataReader) Object(key string) *dataReader {
+	child := &dataReader{prefix: r.path(key), data: map[string]interface{}{}, errs: r.errs, templates: r.templates}
+	v, ok := r.lookup(key)
+	if !ok {
+		return child
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		r.Fail(key, "object", v, "")
+		return child
+	}
+	child.data = m
+	return child
+}
+
+// Objects returns a reader for each object in an array, with paths like
+// "cases[0].handle". Elements that aren't objects are reported and skipped.
+func (r *dataReader) Objects(key string) []*dataReader {
+	var out []*dataReader
+	for i, item := range r.List(key, nil) {
+		path := fmt.Sprintf("%s[%d]", key, i)
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			r.Fail(path, "object", item, "")
+			continue
+		}
+		out = append(out, &dataReader{prefix: r.path(path), data: m, errs: r.errs, templates: r.templates})
+	}
+	return out
+}
+
+// Min records an error if value is below min.
+func (r *dataReader) Min(key string, value, min float64) {
+	if value < min {
+		r.Fail(key, fmt.Sprintf("number >= %v", min), value, "")
+	}
+}
+
+//=============================================== Node Data ===============================================
+
+// Coordinates is a screen position.
+type Coordinates struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// PositionConfig is either the current mouse position or fixed coordinates.
+type PositionConfig struct {
+	Type        string      `json:"type"` // "Mouse" or "Fixed"
+	Coordinates Coordinates `json:"coordinates"`
+}
+
+// SpeedConfig controls how fast a MouseMoveNode travels.
+type SpeedConfig struct {
+	Type      string  `json:"type"` // "Instant" or "Human"
+	Value     float64 `json:"value"`
+	Randomize bool    `json:"randomize"`
+	Variance  float64 `json:"variance"` // percent
+}
+
+// MouseMoveData is the data of a MouseMoveNode.
+type MouseMoveData struct {
+	StartPosition   PositionConfig `json:"startPosition"`
+	EndPosition     PositionConfig `json:"endPosition"`
+	DragWhileMoving bool           `json:"dragWhileMoving"`
+	Speed           SpeedConfig    `json:"speed"`
+	PathType        string         `json:"pathType"` // "Straight" or "Human"
+}
+
+func readPosition(r *dataReader, def string) PositionConfig {
+	coords := r.Object("coordinates")
+	return PositionConfig{
+		Type: r.OneOf("type", def, "Mouse", "Fixed"),
+		Coordinates: Coordinates{
+			X: coords.Float("x", 0),
+			Y: coords.Float("y", 0),
+		},
+	}
+}
+
+func decodeMouseMoveData(data map[string]interface{}) (MouseMoveData, error) {
+	r := newDataReader(data).AllowTemplates()
+	speed := r.Object("speed")
+	d := MouseMoveData{
+		StartPosition:   readPosition(r.Object("startPosition"), "Mouse"),
+		EndPosition:     readPosition(r.Object("endPosition"), "Fixed"),
+		DragWhileMoving: r.Bool("dragWhileMoving", false),
+		Speed: SpeedConfig{
+			Type:      speed.OneOf("type", "Instant", "Instant", "Human"),
+			Value:     speed.Float("value", 500),
+			Randomize: speed.Bool("randomize", false),
+			Variance:  speed.Float("variance", 20),
+		},
+		PathType: r.OneOf("pathType", "Straight", "Straight", "Human"),
+	}
+	speed.Min("value", d.Speed.Value, 0)
+	speed.Min("variance", d.Speed.Variance, 0)
+	return d, r.Err()
+}
+
+// MouseClickData is the data of a MouseClickNode.
+type MouseClickData struct {
+	ButtonType        string   `json:"buttonType"`
+	NumberOfClicks    int      `json:"numberOfClicks"`
+	ClickDelay        float64  `json:"clickDelay"`        // ms between clicks
+	PressReleaseDelay float64  `json:"pressReleaseDelay"` // ms a click is held
+	ReleaseAfterPress bool     `json:"releaseAfterPress"`
+	ScrollDirection   []string `json:"scrollDirection"`
+	ScrollLines       int      `json:"scrollLines"` // negative scrolls up or left
+}
+
+func decodeMouseClickData(data map[string]interface{}) (MouseClickData, error) {
+	r := newDataReader(data)
+	d := MouseClickData{
+		ButtonType:        r.OneOf("buttonType", "left", "left", "right", "middle"),
+		NumberOfClicks:    r.Int("numberOfClicks", 1),
+		ClickDelay:        r.Float("clickDelay", 0.1),
+		PressReleaseDelay: r.Float("pressReleaseDelay", 0.1),
+		ReleaseAfterPress: r.Bool("releaseAfterPress", false),
+		ScrollDirection:   r.Strings("scrollDirection", nil),
+		ScrollLines:       r.Int("scrollLines", 0),
+	}
+	r.Min("numberOfClicks", float64(d.NumberOfClicks), 0)
+	r.Min("clickDelay", d.ClickDelay, 0)
+	r.Min("pressReleaseDelay", d.PressReleaseDelay, 0)
+	for i, dir := range d.ScrollDirection {
+		if dir != "Vertical" && dir != "Horizontal" {
+			r.Fail(fmt.Sprintf("scrollDirection[%d]", i), "one of Vertical, Horizontal", dir, "")
+		}
+	}
+	return d, r.Err()
+}
+
+// TypeStringData is the data of a TypeString node.
+type TypeStringData struct {
+	Text string `json:"text"`
+	// Keys makes {Enter}, {Tab 3} and the like in Text press keys.
+	Keys bool `json:"keys"`
+	// Steps is Text split into text and keys, if Keys is set.
+	Steps []keyStep `json:"-"`
+	// Humanize types a key at a time with human-like timing, if set.
+	Humanize *HumanTyping `json:"humanize,omitempty"`
+	// Mode is "type", or "paste" to paste the text through the clipboard.
+	Mode          string     `json:"mode"`
+	PasteShortcut []keyChord `json:"-"`          // parsed from "pasteShortcut"
+	PasteDelay    float64    `json:"pasteDelay"` // ms after each paste
+}
+
+func decodeTypeStringData(data map[string]interface{}) (TypeStringData, error) {
+	r := newDataReader(data)
+	r.Require("text", "string")
+	d := TypeStringData{
+		Text: r.String("text", ""),
+		Keys: r.Bool("keys", false),
+	}
+	d.Humanize = readHumanTyping(r)
+	d.Mode = r.OneOf("mode", "type", "type", "paste")
+	if d.Mode == "paste" {
+		shortcut := r.String("pasteShortcut", defaultPasteShortcut())
+		chords, err := parseShortcut(shortcut)
+		if err != nil {
+			r.Fail("pasteShortcut", "shortcut", shortcut, err.Error())
+		}
+		d.PasteShortcut = chords
+		d.PasteDelay = r.Float("pasteDelay", 300)
+		r.Min("pasteDelay", d.PasteDelay, 0)
+		if d.Humanize != nil {
+			r.Fail("humanize", "nothing", r.Value("humanize"), "humanized typing doesn't apply to pasting")
+		}
+	}
+	if d.Keys && !hasTemplate(d.Text) {
+		steps, err := parseKeyText(d.Text)
+		if err != nil {
+			r.Fail("text", "text with {Key} names", d.Text, err.Error())
+		}
+		d.Steps = steps
+	}
+	return d, r.Err()
+}
+
+// KeyTapData is the data of a KeyTap node.
+type KeyTapData struct {
+	// Key is a key name or shortcut, such as "enter", "ctrl+s" or the
+	// sequence "ctrl+k ctrl+c".
+	Key       string   `json:"key"`
+	Modifiers []string `json:"modifiers"` // held while tapping, e.g. "ctrl"
+	// Chords are the parsed Key, with Modifiers added to each.
+	Chords []keyChord `json:"-"`
+}
+
+func decodeKeyTapData(data map[string]interface{}) (KeyTapData, error) {
+	r := newDataReader(data)
+	r.Require("key", "string")
+	d := KeyTapData{
+		Key:       r.String("key", ""),
+		Modifiers: r.Strings("modifiers", nil),
+	}
+	if hasTemplate(d.Key) || slices.ContainsFunc(d.Modifiers, hasTemplate) {
+		return d, r.Err()
+	}
+	modifiers, err := parseModifiers(d.Modifiers)
+	if err != nil {
+		r.Fail("modifiers", "ctrl, alt, shift or cmd", d.Modifiers, err.Error())
+	}
+	switch {
+	case !r.Has("key"):
+	case d.Key == "":
+		r.Fail("key", "non-empty string", d.Key, "")
+	default:
+		d.Chords, err = parseShortcut(d.Key)
+		if err != nil {
+			r.Fail("key", "key name or shortcut", d.Key, err.Error())
+		}
+		for i, c := range d.Chords {
+			d.Chords[i].Modifiers, _ = parseModifiers(append(c.Modifiers, modifiers...))
+		}
+	}
+	return d, r.Err()
+}
+
+// KeyPressData is the data of a KeyPressNode. Press taps the key, holding it
+// for PressDuration; Hold leaves it down for later nodes; Release lets go of
+// it, or of every held key if Key is empty.
+type KeyPressData struct {
+	Key           string   `json:"key"`
+	Action        string   `json:"action"`        // "Press", "Hold" or "Release"
+	Modifiers     []string `json:"modifiers"`     // held around the key, e.g. "ctrl"
+	PressDuration float64  `json:"pressDuration"` // ms, for Press
+}
+
+func decodeKeyPressData(data map[string]interface{}) (KeyPressData, error) {
+	r := newDataReader(data).AllowTemplates()
+	d := KeyPressData{
+		Key:           r.String("key", ""),
+		Action:        r.OneOf("action", "Press", "Press", "Hold", "Release"),
+		Modifiers:     r.Strings("modifiers", nil),
+		PressDuration: r.Float("pressDuration", 50),
+	}
+	// The editor picks a single modifier, "None" for none
+	if m := r.String("modifier", "None"); m != "None" && m != "" {
+		d.Modifiers = append(d.Modifiers, m)
+	}
+	if !slices.ContainsFunc(d.Modifiers, hasTemplate) {
+		modifiers, err := parseModifiers(d.Modifiers)
+		if err != nil {
+			r.Fail("modifiers", "ctrl, alt, shift or cmd", d.Modifiers, err.Error())
+		}
+		d.Modifiers = modifiers
+	}
+	switch {
+	case d.Key == "" && d.Action != "Release":
+		r.Fail("key", "non-empty string", d.Key, "only Release may leave the key empty")
+	case d.Key != "" && !hasTemplate(d.Key):
+		key, err := parseKey(d.Key)
+		if err != nil {
+			r.Fail("key", "key name", d.Key, err.Error())
+		}
+		d.Key = key
+	}
+	r.Min("pressDuration", d.PressDuration, 0)
+	return d, r.Err()
+}
+
+// DelayData is the data of a DelayNode. Times are in milliseconds.
+type DelayData struct {
+	DelayType string  `json:"delayType"` // "Fixed" or "Random"
+	Time      float64 `json:"time"`
+	MinTime   float64 `json:"minTime"`
+	MaxTime   float64 `json:"maxTime"`
+}
+
+func decodeDelayData(data map[string]interface{}) (DelayData, error) {
+	r := newDataReader(data).AllowTemplates()
+	d := DelayData{
+		DelayType: r.OneOf("delayType", "Fixed", "Fixed", "Random"),
+		Time:      r.Float("time", 1000),
+		MinTime:   r.Float("minTime", 500),
+		MaxTime:   r.Float("maxTime", 1500),
+	}
+	r.Min("time", d.Time, 0)
+	r.Min("minTime", d.MinTime, 0)
+	if d.DelayType == "Random" && d.MinTime > d.MaxTime {
+		r.Fail("minTime", fmt.Sprintf("number <= maxTime (%v)", d.MaxTime), d.MinTime, "minTime cannot be greater than maxTime")
+	}
+	return d, r.Err()
+}
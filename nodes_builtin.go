@@ -0,0 +1,472 @@
+// nodes_builtin.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExecutor("StartNode", startExecutor{})
+	RegisterExecutor("MouseMoveNode", mouseMoveExecutor{})
+	RegisterExecutor("MouseClickNode", mouseClickExecutor{})
+	RegisterExecutor("TypeString", typeStringExecutor{})
+	RegisterExecutor("KeyTap", keyTapExecutor{})
+	RegisterExecutor("KeyPressNode", keyPressExecutor{})
+	RegisterExecutor("DelayNode", delayExecutor{})
+}
+
+//=============================================== Start ===============================================
+
+type startExecutor struct{}
+
+func (startExecutor) Validate(node Node) error { return nil }
+
+func (startExecutor) Describe(node Node) string { return "Start the flow" }
+
+func (startExecutor) Execute(ec *ExecContext) error {
+	log.Printf("Flow Started - Task ID: %s", ec.Task.ID)
+	return nil
+}
+
+//=============================================== Mouse Move ===============================================
+
+type mouseMoveExecutor struct{}
+
+func (mouseMoveExecutor) Validate(node Node) error {
+	_, err := decodeMouseMoveData(node.Data)
+	return err
+}
+
+func (mouseMoveExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMouseMoveData(data)
+}
+
+func (mouseMoveExecutor) Describe(node Node) string {
+	d, err := decodeMouseMoveData(node.Data)
+	if err != nil || d.EndPosition.Type == "Mouse" {
+		return "Move the mouse"
+	}
+	return fmt.Sprintf("Move the mouse to %v, %v", d.EndPosition.Coordinates.X, d.EndPosition.Coordinates.Y)
+}
+
+func (mouseMoveExecutor) Execute(ec *ExecContext) error {
+	log.Printf("MoveMouse task starting - Data: %+v", ec.Task.Data)
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeMouseMoveData(data)
+	if err != nil {
+		return err
+	}
+
+	// Get current mouse position for 'Mouse' type positions
+	currentX, currentY := ec.Driver.Location()
+	resolve := func(pos PositionConfig) (int, int) {
+		if pos.Type == "Mouse" {
+			return currentX, currentY
+		}
+		return int(pos.Coordinates.X), int(pos.Coordinates.Y)
+	}
+	startX, startY := resolve(d.StartPosition)
+	endX, endY := resolve(d.EndPosition)
+
+	// Move to start position if not already there
+	ec.Driver.Move(startX, startY)
+
+	// Calculate final speed with randomization if enabled
+	finalSpeed := d.Speed.Value
+	if d.Speed.Randomize {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		varianceAmount := d.Speed.Value * (d.Speed.Variance / 100.0)
+		finalSpeed += (r.Float64()*2 - 1) * varianceAmount
+	}
+
+	// Execute movement based on configuration, at the configured speed
+	motion := MouseMotion{X: endX, Y: endY, Drag: d.DragWhileMoving, Sleep: int(finalSpeed)}
+	if d.Speed.Type != "Instant" {
+		motion.Smooth = true
+		motion.Delay = int(finalSpeed)
+		// Straight paths use minimal randomization, human-like paths more. TODO: use relative?
+		motion.Low, motion.High = 1.0, 2.0
+		if d.PathType == "Straight" {
+			motion.High = 1.2
+		}
+	}
+	return ec.Driver.MoveWithSpeed(motion)
+}
+
+//=============================================== Mouse Click ===============================================
+
+type mouseClickExecutor struct{}
+
+func (mouseClickExecutor) Validate(node Node) error {
+	_, err := decodeMouseClickData(node.Data)
+	return err
+}
+
+func (mouseClickExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	return decodeMouseClickData(ec.Task.Data)
+}
+
+func (mouseClickExecutor) Describe(node Node) string {
+	d, err := decodeMouseClickData(node.Data)
+	if err != nil {
+		return "Click the mouse"
+	}
+	return fmt.Sprintf("Click the %s button %d time(s)", d.ButtonType, d.NumberOfClicks)
+}
+
+func (mouseClickExecutor) Execute(ec *ExecContext) error {
+	log.Printf("Click task starting - Data: %+v", ec.Task.Data)
+	d, err := decodeMouseClickData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	clickDuration := time.Duration(d.ClickDelay) * time.Millisecond
+	pressDuration := time.Duration(d.PressReleaseDelay) * time.Millisecond
+
+	//TODO: standardise execution in order for all blcoks by how the block is displayed e.g clicks first then scroll
+	// Perform the click actions
+	if d.NumberOfClicks > 0 {
+		log.Printf("Performing %v clicks with %v delay and %v press duration",
+			d.NumberOfClicks, clickDuration, pressDuration)
+
+		for i := 0; i < d.NumberOfClicks; i++ {
+			if d.ReleaseAfterPress {
+				ec.Driver.MouseDown(d.ButtonType)
+				// Always release, even if the run stops mid-press
+				err := ec.Sleep(pressDuration)
+				ec.Driver.MouseUp(d.ButtonType)
+				if err != nil {
+					return err
+				}
+			} else {
+				ec.Driver.Click(d.ButtonType)
+			}
+
+			if i < d.NumberOfClicks-1 {
+				if err := ec.Sleep(clickDuration); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Handle scrolling if configured
+	if d.ScrollLines != 0 {
+		for _, direction := range d.ScrollDirection {
+			log.Printf("Scrolling %s with %v lines", direction, d.ScrollLines)
+
+			switch direction {
+			case "Vertical":
+				// For vertical scrolling, positive is down, negative is up
+				if d.ScrollLines < 0 {
+					ec.Driver.ScrollDir(-d.ScrollLines, "up")
+				} else {
+					ec.Driver.ScrollDir(d.ScrollLines, "down")
+				}
+			case "Horizontal":
+				// For horizontal scrolling, we use the x,y coordinates method
+				// Positive scrollAmount moves right, negative moves left
+				ec.Driver.Scroll(d.ScrollLines, 0)
+			}
+			if err := ec.Sleep(100 * time.Millisecond); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//=============================================== Keyboard ===============================================
+
+type typeStringExecutor struct{}
+
+func (typeStringExecutor) Validate(node Node) error {
+	_, err := decodeTypeStringData(node.Data)
+	return err
+}
+
+func (typeStringExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTypeStringData(data)
+}
+
+func (typeStringExecutor) Describe(node Node) string {
+	d, _ := decodeTypeStringData(node.Data)
+	if d.Mode == "paste" {
+		return fmt.Sprintf("Paste %q", d.Text)
+	}
+	if d.Humanize != nil {
+		return fmt.Sprintf("Type %q at %v wpm", d.Text, d.Humanize.WPM)
+	}
+	return fmt.Sprintf("Type %q", d.Text)
+}
+
+func (typeStringExecutor) Execute(ec *ExecContext) error {
+	log.Printf("TypeString task starting - Data: %+v", ec.Task.Data)
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeTypeStringData(data)
+	if err != nil {
+		return err
+	}
+	steps := d.Steps
+	if !d.Keys {
+		steps = []keyStep{{Text: d.Text}}
+	}
+	if d.Mode == "paste" {
+		log.Printf("Pasting text: %s", d.Text)
+		if err := pasteSteps(ec, steps, d); err != nil {
+			return err
+		}
+		return ec.Sleep(100 * time.Millisecond)
+	}
+
+	log.Printf("Typing text: %s", d.Text)
+	var rng *rand.Rand
+	if d.Humanize != nil {
+		rng = d.Humanize.rng()
+	}
+	for _, step := range steps {
+		switch {
+		case step.Text == "":
+		case d.Humanize != nil:
+			if err := typeHuman(ec, step.Text, d.Humanize, rng); err != nil {
+				return err
+			}
+		default:
+			ec.Driver.TypeStr(step.Text)
+		}
+		for i := 0; i < step.Repeat; i++ {
+			if err := tapChords(ec, step.Chords); err != nil {
+				return err
+			}
+		}
+	}
+	return ec.Sleep(100 * time.Millisecond)
+}
+
+type keyTapExecutor struct{}
+
+func (keyTapExecutor) Validate(node Node) error {
+	_, err := decodeKeyTapData(node.Data)
+	return err
+}
+
+func (keyTapExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeyTapData(data)
+}
+
+func (keyTapExecutor) Describe(node Node) string {
+	d, _ := decodeKeyTapData(node.Data)
+	if len(d.Chords) == 1 && len(d.Chords[0].Modifiers) == 0 {
+		return fmt.Sprintf("Tap the %s key", d.Chords[0].Key)
+	}
+	if len(d.Chords) > 0 {
+		chords := make([]string, len(d.Chords))
+		for i, c := range d.Chords {
+			chords[i] = c.String()
+		}
+		return "Tap " + strings.Join(chords, " ")
+	}
+	return fmt.Sprintf("Tap %s", d.Key)
+}
+
+func (keyTapExecutor) Execute(ec *ExecContext) error {
+	log.Printf("KeyTap task starting - Data: %+v", ec.Task.Data)
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeKeyTapData(data)
+	if err != nil {
+		return err
+	}
+	log.Printf("Tapping key: %s %v", d.Key, d.Modifiers)
+	if err := tapChords(ec, d.Chords); err != nil {
+		return err
+	}
+	return ec.Sleep(100 * time.Millisecond)
+}
+
+// tapChords taps each chord in turn, pausing briefly between them so
+// sequences like "ctrl+k ctrl+c" register.
+func tapChords(ec *ExecContext, chords []keyChord) error {
+	for i, c := range chords {
+		if i > 0 {
+			if err := ec.Sleep(50 * time.Millisecond); err != nil {
+				return err
+			}
+		}
+		if err := ec.Driver.KeyTap(c.Key, c.Modifiers...); err != nil {
+			return fmt.Errorf("KeyTap %s failed: %v", c, err)
+		}
+	}
+	return nil
+}
+
+//=============================================== Key Press ===============================================
+
+// keyPressExecutor presses, holds or releases a key. Modifiers go down as
+// keys of their own before the key and come up after it, so a key held by
+// one node can be released by another.
+type keyPressExecutor struct{}
+
+func (keyPressExecutor) Validate(node Node) error {
+	_, err := decodeKeyPressData(node.Data)
+	return err
+}
+
+func (keyPressExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeyPressData(data)
+}
+
+func (keyPressExecutor) Describe(node Node) string {
+	d, err := decodeKeyPressData(node.Data)
+	if err != nil {
+		return "Press a key"
+	}
+	keys := strings.Join(append(slices.Clone(d.Modifiers), d.Key), "+")
+	switch {
+	case d.Action == "Hold":
+		return fmt.Sprintf("Hold %s down", keys)
+	case d.Action == "Release" && d.Key == "":
+		return "Release every held key"
+	case d.Action == "Release":
+		return fmt.Sprintf("Release %s", keys)
+	}
+	return fmt.Sprintf("Press %s for %v ms", keys, d.PressDuration)
+}
+
+func (keyPressExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeKeyPressData(data)
+	if err != nil {
+		return err
+	}
+
+	switch d.Action {
+	case "Hold":
+		return keysDown(ec.Driver, d.Key, d.Modifiers)
+	case "Release":
+		if d.Key == "" {
+			log.Printf("Released %v", ec.run.held.releaseKeys())
+			return nil
+		}
+		return keysUp(ec.Driver, d.Key, d.Modifiers)
+	}
+
+	if err := keysDown(ec.Driver, d.Key, d.Modifiers); err != nil {
+		return err
+	}
+	// Always release, even if the run stops mid-press
+	err = ec.Sleep(time.Duration(d.PressDuration * float64(time.Millisecond)))
+	if upErr := keysUp(ec.Driver, d.Key, d.Modifiers); err == nil {
+		err = upErr
+	}
+	return err
+}
+
+// keysDown presses modifiers and then key, releasing them again on failure.
+func keysDown(driver InputDriver, key string, modifiers []string) error {
+	for i, k := range append(slices.Clone(modifiers), key) {
+		if err := driver.KeyDown(k); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				driver.KeyUp(modifiers[j])
+			}
+			return fmt.Errorf("KeyDown %s failed: %v", k, err)
+		}
+	}
+	return nil
+}
+
+// keysUp releases key and then modifiers, all of them even if one fails.
+func keysUp(driver InputDriver, key string, modifiers []string) error {
+	var first error
+	keys := append([]string{key}, modifiers...)
+	slices.Reverse(keys[1:])
+	for _, k := range keys {
+		if err := driver.KeyUp(k); err != nil && first == nil {
+			first = fmt.Errorf("KeyUp %s failed: %v", k, err)
+		}
+	}
+	return first
+}
+
+//=============================================== Delay ===============================================
+
+type delayExecutor struct{}
+
+func (delayExecutor) Validate(node Node) error {
+	_, err := decodeDelayData(node.Data)
+	return err
+}
+
+func (delayExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDelayData(data)
+}
+
+func (delayExecutor) Describe(node Node) string {
+	d, _ := decodeDelayData(node.Data)
+	if d.DelayType == "Random" {
+		return fmt.Sprintf("Wait between %v and %v ms", d.MinTime, d.MaxTime)
+	}
+	return fmt.Sprintf("Wait %v ms", d.Time)
+}
+
+func (delayExecutor) Execute(ec *ExecContext) error {
+	log.Printf("Delay task starting - Data: %+v", ec.Task.Data)
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeDelayData(data)
+	if err != nil {
+		return err
+	}
+
+	switch d.DelayType {
+	case "Random":
+		// Generate random delay using a local random generator
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		delay := d.MinTime + r.Float64()*(d.MaxTime-d.MinTime)
+		log.Printf("Executing random delay between %v and %v milliseconds. Selected delay: %v milliseconds", d.MinTime, d.MaxTime, delay)
+		return ec.Sleep(time.Duration(delay) * time.Millisecond)
+
+	default:
+		log.Printf("Executing fixed delay of %v milliseconds", d.Time)
+		return ec.Sleep(time.Duration(d.Time) * time.Millisecond)
+	}
+}
@@ -0,0 +1,158 @@
+// nodes_builtin_test.go
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMouseMoveNode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "instant",
+			data: `{"endPosition":{"type":"Fixed","coordinates":{"x":100,"y":200}}}`,
+			want: []string{"move(0, 0)", "mouseSleep(500)", "move(100, 200)", "mouseSleep(100)"},
+		},
+		{
+			name: "straight",
+			data: `{"startPosition":{"type":"Fixed","coordinates":{"x":10,"y":20}},
+				"endPosition":{"type":"Fixed","coordinates":{"x":30,"y":40}},
+				"speed":{"type":"Human","value":5},"pathType":"Straight"}`,
+			want: []string{"move(10, 20)", "mouseSleep(5)", "moveSmooth(30, 40, 1, 1.2, 5)", "mouseSleep(100)"},
+		},
+		{
+			name: "human drag",
+			data: `{"endPosition":{"type":"Fixed","coordinates":{"x":30,"y":40}},
+				"speed":{"type":"Human","value":5},"pathType":"Human","dragWhileMoving":true}`,
+			want: []string{"move(0, 0)", "mouseSleep(5)", "mouseDown(left)", "moveSmooth(30, 40, 1, 2, 5)", "mouseUp(left)", "mouseSleep(100)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFlow(t, chain(node("m", "MouseMoveNode", tt.data)))
+			assertActions(t, got, tt.want...)
+		})
+	}
+}
+
+func TestParallelMouseMoveSpeeds(t *testing.T) {
+	move := func(x, speed int) string {
+		return fmt.Sprintf(`{"startPosition":{"type":"Fixed","coordinates":{"x":0,"y":0}},
+			"endPosition":{"type":"Fixed","coordinates":{"x":%d,"y":%d}},
+			"speed":{"type":"Human","value":%d},"pathType":"Straight"}`, x, x, speed)
+	}
+	flow := withOptions(graph([]testNode{
+		node("slow", "MouseMoveNode", move(100, 50)),
+		node("fast", "MouseMoveNode", move(200, 2)),
+	}, "start->slow", "start->fast"), `{"policy":"parallel"}`)
+
+	slow := []string{"mouseSleep(50)", "moveSmooth(100, 100, 1, 1.2, 50)", "mouseSleep(100)"}
+	fast := []string{"mouseSleep(2)", "moveSmooth(200, 200, 1, 1.2, 2)", "mouseSleep(100)"}
+	for i := 0; i < 20; i++ {
+		// Each move runs at its own speed, with the default restored before the other starts
+		got := withoutOp(runFlow(t, flow), "move")
+		if !slices.Equal(got, append(slices.Clone(slow), fast...)) && !slices.Equal(got, append(slices.Clone(fast), slow...)) {
+			t.Fatalf("run %d: moves interleaved: %q", i, got)
+		}
+	}
+}
+
+func TestMouseClickNode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"single", `{}`, []string{"click(left)"}},
+		{"double right", `{"buttonType":"right","numberOfClicks":2}`, []string{"click(right)", "click(right)"}},
+		{"press and release", `{"releaseAfterPress":true,"pressReleaseDelay":5}`, []string{"mouseDown(left)", "mouseUp(left)"}},
+		{"scroll up", `{"numberOfClicks":0,"scrollDirection":["Vertical"],"scrollLines":-3}`, []string{"scrollDir(3, up)"}},
+		{"scroll down and right", `{"numberOfClicks":0,"scrollDirection":["Vertical","Horizontal"],"scrollLines":2}`, []string{"scrollDir(2, down)", "scroll(2, 0)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFlow(t, chain(node("c", "MouseClickNode", tt.data)))
+			assertActions(t, got, tt.want...)
+		})
+	}
+}
+
+func TestKeyboardNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		node testNode
+		want []string
+	}{
+		{"type", node("t", "TypeString", `{"text":"hello"}`), []string{"type(hello)"}},
+		{"type with keys", node("t", "TypeString", `{"text":"a{Enter}{Tab 2}{{}","keys":true}`),
+			[]string{"type(a)", "keyTap(enter)", "keyTap(tab)", "keyTap(tab)", "type({)"}},
+		{"braces without keys", node("t", "TypeString", `{"text":"{Enter}"}`), []string{"type({Enter})"}},
+		{"tap", node("k", "KeyTap", `{"key":"Enter"}`), []string{"keyTap(enter)"}},
+		{"tap with modifiers", node("k", "KeyTap", `{"key":"s","modifiers":["control"]}`), []string{"keyTap(ctrl+s)"}},
+		{"tap sequence", node("k", "KeyTap", `{"key":"ctrl+k ctrl+c"}`), []string{"keyTap(ctrl+k)", "keyTap(ctrl+c)"}},
+		{"press", node("p", "KeyPressNode", `{"key":"a","modifiers":["shift"],"pressDuration":1}`),
+			[]string{"keyDown(shift)", "keyDown(a)", "keyUp(a)", "keyUp(shift)"}},
+		// Keys still held when the run ends are released, modifiers last
+		{"hold", node("p", "KeyPressNode", `{"key":"x","action":"Hold","modifier":"Ctrl"}`),
+			[]string{"keyDown(ctrl)", "keyDown(x)", "keyUp(x)", "keyUp(ctrl)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFlow(t, chain(tt.node))
+			assertActions(t, got, tt.want...)
+		})
+	}
+}
+
+func TestHoldAndRelease(t *testing.T) {
+	got := runFlow(t, chain(
+		node("h1", "KeyPressNode", `{"key":"shift","action":"Hold"}`),
+		node("c", "MouseClickNode", `{}`),
+		node("h2", "KeyPressNode", `{"key":"w","action":"Hold"}`),
+		node("r", "KeyPressNode", `{"action":"Release"}`),
+		node("t", "KeyTap", `{"key":"z"}`),
+	))
+	assertActions(t, got,
+		"keyDown(shift)", "click(left)", "keyDown(w)",
+		"keyUp(w)", "keyUp(shift)",
+		"keyTap(z)")
+}
+
+func TestDelayNodeHasNoInput(t *testing.T) {
+	got := runFlow(t, chain(
+		node("a", "KeyTap", `{"key":"a"}`),
+		node("d", "DelayNode", `{"time":5}`),
+		node("b", "KeyTap", `{"key":"b"}`),
+	))
+	assertActions(t, got, "keyTap(a)", "keyTap(b)")
+}
+
+func TestKeyNamesAreValidated(t *testing.T) {
+	report := validateFlow(parseFlow(t, chain(
+		node("a", "KeyTap", `{"key":"entr"}`),
+		node("b", "KeyPressNode", `{"key":"escpae"}`),
+		node("c", "TypeString", `{"text":"{Tabb}","keys":true}`),
+		node("d", "KeyTap", `{"key":"{{key}}"}`),
+	)))
+	want := map[string]string{
+		"a": `unknown key "entr" (did you mean "enter"?)`,
+		"b": `unknown key "escpae" (did you mean "escape"?)`,
+		"c": `unknown key "Tabb" (did you mean "tab"?)`,
+	}
+	errs := report.Errors()
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %+v", len(errs), len(want), errs)
+	}
+	for _, issue := range errs {
+		if msg := want[issue.NodeIDs[0]]; !strings.Contains(issue.Message, msg) {
+			t.Errorf("node %s: got %q, want it to contain %q", issue.NodeIDs[0], issue.Message, msg)
+		}
+	}
+}
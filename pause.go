@@ -0,0 +1,104 @@
+// pause.go
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pauseGate lets a run be suspended and resumed. Waiters block while the
+// gate is paused; sleepers are woken when it pauses so they can stop their
+// timers and resume with the remaining time.
+type pauseGate struct {
+	mu       sync.Mutex
+	paused   bool
+	pausedCh chan struct{} // closed while paused
+	resumeCh chan struct{} // closed while running
+}
+
+func newPauseGate() *pauseGate {
+	resumeCh := make(chan struct{})
+	close(resumeCh)
+	return &pauseGate{
+		pausedCh: make(chan struct{}),
+		resumeCh: resumeCh,
+	}
+}
+
+// Pause suspends the gate. It reports false if it was already paused.
+func (g *pauseGate) Pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.resumeCh = make(chan struct{})
+	close(g.pausedCh)
+	return true
+}
+
+// Resume releases the gate. It reports false if it was not paused.
+func (g *pauseGate) Resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	g.pausedCh = make(chan struct{})
+	close(g.resumeCh)
+	return true
+}
+
+// IsPaused reports whether the gate is paused.
+func (g *pauseGate) IsPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+func (g *pauseGate) channels() (paused, resumed <-chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pausedCh, g.resumeCh
+}
+
+// Wait blocks while the gate is paused, or until ctx is done.
+func (g *pauseGate) Wait(ctx context.Context) error {
+	_, resumed := g.channels()
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sleep waits for d of unpaused time. Pausing stops the clock; it restarts
+// with the remaining time on resume. It returns early with ctx's error if ctx
+// is done.
+func (g *pauseGate) Sleep(ctx context.Context, d time.Duration) error {
+	remaining := d
+	for remaining > 0 {
+		if err := g.Wait(ctx); err != nil {
+			return err
+		}
+		paused, _ := g.channels()
+		start := time.Now()
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-paused:
+			timer.Stop()
+			remaining -= time.Since(start)
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,58 @@
+// pause_test.go
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseExtendsDelay(t *testing.T) {
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	began := time.Now()
+	if _, err := app.StartExecution(chain(
+		node("wait", "DelayNode", `{"time":200}`),
+		node("after", "TypeString", `{"text":"done"}`),
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := app.PauseExecution(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.PauseExecution(); err == nil {
+		t.Error("a paused run was paused again")
+	}
+	// Well past the end of the delay, had it kept running
+	time.Sleep(300 * time.Millisecond)
+	if actions := driver.ActionStrings(); len(actions) != 0 {
+		t.Fatalf("ran while paused: %q", actions)
+	}
+	resumed := time.Now()
+	if err := app.ResumeExecution(); err != nil {
+		t.Fatal(err)
+	}
+	for len(driver.ActionStrings()) == 0 && time.Since(resumed) < time.Second {
+		time.Sleep(time.Millisecond)
+	}
+	// The delay picks up its remaining 100 ms instead of starting over
+	if rest := time.Since(resumed); rest < 80*time.Millisecond || rest > 180*time.Millisecond {
+		t.Errorf("the delay ended %v after resuming, want the remaining 100ms", rest)
+	}
+	if total := time.Since(began); total < 480*time.Millisecond {
+		t.Errorf("the delay ended after %v, less than itself and the pause", total)
+	}
+	waitIdle(t, app)
+
+	assertActions(t, driver.ActionStrings(), "type(done)")
+	if len(events.named("execution-paused")) != 1 || len(events.named("execution-resumed")) != 1 {
+		t.Errorf("%d execution-paused and %d execution-resumed events, want 1 each",
+			len(events.named("execution-paused")), len(events.named("execution-resumed")))
+	}
+	if err := app.ResumeExecution(); err == nil {
+		t.Error("an ended run was resumed")
+	}
+}
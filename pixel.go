@@ -0,0 +1,164 @@
+// pixel.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExecutor("ColorPicker", colorPickerExecutor{})
+}
+
+// ColorPicker output handles in branch mode.
+const (
+	HandleMatch   = "match"
+	HandleNoMatch = "noMatch"
+)
+
+// ColorPickerData is the data of a ColorPicker node. Times are in milliseconds.
+type ColorPickerData struct {
+	Mode         string  `json:"mode"` // "wait" or "branch"
+	X            int     `json:"x"`
+	Y            int     `json:"y"`
+	Color        string  `json:"color"`     // target colour, "#rrggbb"
+	Tolerance    int     `json:"tolerance"` // per channel, 0-255
+	PollInterval float64 `json:"pollInterval"`
+	Timeout      float64 `json:"timeout"` // 0: wait forever (wait) or check once (branch)
+}
+
+func decodeColorPickerData(data map[string]interface{}) (ColorPickerData, error) {
+	r := newDataReader(data).AllowTemplates()
+	d := ColorPickerData{
+		Mode:         r.OneOf("mode", "wait", "wait", "branch"),
+		X:            r.Int("x", 0),
+		Y:            r.Int("y", 0),
+		Tolerance:    r.Int("tolerance", 10),
+		PollInterval: r.Float("pollInterval", 100),
+	}
+	defaultTimeout := 10000.0
+	if d.Mode == "branch" {
+		defaultTimeout = 0
+	}
+	d.Timeout = r.Float("timeout", defaultTimeout)
+
+	// The editor keeps the picked colour under colorStore
+	key := "color"
+	if !r.Has(key) && r.Has("colorStore") {
+		key = "colorStore"
+	}
+	if r.Require(key, "hex colour") {
+		d.Color = r.String(key, "")
+		if _, err := parseHexColor(d.Color); err != nil && !hasTemplate(d.Color) {
+			r.Fail(key, "hex colour", d.Color, "")
+		}
+	}
+
+	r.Min("tolerance", float64(d.Tolerance), 0)
+	r.Min("pollInterval", d.PollInterval, 1)
+	r.Min("timeout", d.Timeout, 0)
+	return d, r.Err()
+}
+
+// colorPickerExecutor reads the colour at a coordinate and either waits until
+// it matches the target or branches on whether it does.
+type colorPickerExecutor struct{}
+
+func (colorPickerExecutor) Validate(node Node) error {
+	_, err := decodeColorPickerData(node.Data)
+	return err
+}
+
+func (colorPickerExecutor) Handles(node Node) []string {
+	d, _ := decodeColorPickerData(node.Data)
+	if d.Mode != "branch" {
+		return nil
+	}
+	return []string{HandleMatch, HandleNoMatch}
+}
+
+func (colorPickerExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeColorPickerData(data)
+}
+
+func (colorPickerExecutor) Describe(node Node) string {
+	d, err := decodeColorPickerData(node.Data)
+	if err != nil {
+		return "Check a pixel colour"
+	}
+	if d.Mode == "branch" {
+		return fmt.Sprintf("Branch on whether pixel %d, %d is %s", d.X, d.Y, d.Color)
+	}
+	return fmt.Sprintf("Wait until pixel %d, %d is %s", d.X, d.Y, d.Color)
+}
+
+func (colorPickerExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeColorPickerData(data)
+	if err != nil {
+		return err
+	}
+	want, err := parseHexColor(d.Color)
+	if err != nil {
+		return err
+	}
+
+	matched, got, err := pollPixel(ec, d, want)
+	if err != nil {
+		return err
+	}
+	log.Printf("Pixel %d, %d is #%s (target %s, matched %v)", d.X, d.Y, got, d.Color, matched)
+
+	if d.Mode == "branch" {
+		handle := HandleNoMatch
+		if matched {
+			handle = HandleMatch
+		}
+		followBranch(ec, handle)
+		return nil
+	}
+	if !matched {
+		return fmt.Errorf("pixel %d, %d is still #%s after %v ms, not within %d of %s", d.X, d.Y, got, d.Timeout, d.Tolerance, d.Color)
+	}
+	return nil
+}
+
+// pollPixel reads the pixel until it is within tolerance of want or the
+// timeout has passed, returning the last colour read. Time spent paused
+// doesn't count towards the timeout.
+func pollPixel(ec *ExecContext, d ColorPickerData, want rgb) (bool, string, error) {
+	timeout := time.Duration(d.Timeout * float64(time.Millisecond))
+	interval := time.Duration(d.PollInterval * float64(time.Millisecond))
+	var elapsed time.Duration
+	for {
+		start := time.Now()
+		got := strings.ToLower(ec.Driver.PixelColor(d.X, d.Y))
+		c, err := parseHexColor(got)
+		if err != nil {
+			return false, got, fmt.Errorf("reading pixel %d, %d: %w", d.X, d.Y, err)
+		}
+		if colorDistance(want, c) <= d.Tolerance {
+			return true, got, nil
+		}
+		elapsed += time.Since(start)
+
+		checkOnce := timeout == 0 && d.Mode == "branch"
+		if checkOnce || (timeout > 0 && elapsed >= timeout) {
+			return false, got, nil
+		}
+		if err := ec.Sleep(interval); err != nil {
+			return false, got, err
+		}
+		elapsed += interval
+	}
+}
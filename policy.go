@@ -0,0 +1,202 @@
+// policy.go
+
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ExecutionPolicy decides how ready nodes are scheduled.
+type ExecutionPolicy string
+
+const (
+	// PolicySequentialDFS runs one node at a time, following each branch to
+	// its end before starting the next (first edge first).
+	PolicySequentialDFS ExecutionPolicy = "sequential-dfs"
+	// PolicySequentialBFS runs one node at a time, level by level.
+	PolicySequentialBFS ExecutionPolicy = "sequential-bfs"
+	// PolicyParallel runs every ready node at once on the worker pool. Input
+	// still goes through an arbiter so only one action touches the physical
+	// mouse and keyboard at a time.
+	PolicyParallel ExecutionPolicy = "parallel"
+)
+
+// RunOptions configures a single execution. It travels with the flowchart
+// JSON passed to StartExecution under "options".
+type RunOptions struct {
+	Policy ExecutionPolicy `json:"policy,omitempty"`
+	Debug  DebugMode       `json:"debug,omitempty"`
+	// Timeout limits the run in milliseconds, overriding the timeouts.run setting
+	Timeout float64 `json:"timeout,omitempty"`
+}
+
+// withDefaults fills in unset options.
+func (o RunOptions) withDefaults() RunOptions {
+	if o.Policy == "" {
+		o.Policy = PolicySequentialDFS
+	}
+	return o
+}
+
+// validate checks option values.
+func (o RunOptions) validate() error {
+	switch o.Policy {
+	case PolicySequentialDFS, PolicySequentialBFS, PolicyParallel:
+	default:
+		return fmt.Errorf("unknown execution policy %q", o.Policy)
+	}
+	switch o.Debug {
+	case DebugOff, DebugStep, DebugBreakpoints:
+	default:
+		return fmt.Errorf("unknown debug mode %q", o.Debug)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout must be >= 0, got %v", o.Timeout)
+	}
+	return nil
+}
+
+// sequential reports whether at most one node may run at a time.
+func (o RunOptions) sequential() bool {
+	return o.Policy != PolicyParallel
+}
+
+//=============================================== Frontier ===============================================
+
+// frontier holds tasks that are ready to run but not yet dispatched.
+type frontier struct {
+	policy ExecutionPolicy
+	tasks  []Task
+}
+
+// push adds the ready dependents of one completed node, in edge order.
+func (f *frontier) push(tasks ...Task) {
+	if f.policy == PolicySequentialDFS {
+		// Stack: push in reverse so the first edge is popped first
+		for i := len(tasks) - 1; i >= 0; i-- {
+			f.tasks = append(f.tasks, tasks[i])
+		}
+		return
+	}
+	f.tasks = append(f.tasks, tasks...)
+}
+
+// pop removes the next task to run.
+func (f *frontier) pop() (Task, bool) {
+	if len(f.tasks) == 0 {
+		return Task{}, false
+	}
+	var task Task
+	if f.policy == PolicySequentialDFS {
+		task = f.tasks[len(f.tasks)-1]
+		f.tasks = f.tasks[:len(f.tasks)-1]
+	} else {
+		task = f.tasks[0]
+		f.tasks = f.tasks[1:]
+	}
+	return task, true
+}
+
+func (f *frontier) len() int {
+	return len(f.tasks)
+}
+
+//=============================================== Input Arbiter ===============================================
+
+// inputArbiter wraps an InputDriver so that concurrent tasks take turns:
+// each action holds the lock for its duration, so a smooth move can't be
+// interleaved with another branch's click or keystroke.
+type inputArbiter struct {
+	mu     sync.Mutex
+	driver InputDriver
+}
+
+func newInputArbiter(driver InputDriver) *inputArbiter {
+	return &inputArbiter{driver: driver}
+}
+
+func (a *inputArbiter) Move(x, y int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.driver.Move(x, y)
+}
+
+func (a *inputArbiter) MoveSmooth(x, y int, low, high float64, delay int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.MoveSmooth(x, y, low, high, delay)
+}
+
+func (a *inputArbiter) MouseDown(button string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.MouseDown(button)
+}
+
+func (a *inputArbiter) MouseUp(button string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.MouseUp(button)
+}
+
+func (a *inputArbiter) Click(button string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.driver.Click(button)
+}
+
+func (a *inputArbiter) Scroll(x, y int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.driver.Scroll(x, y)
+}
+
+func (a *inputArbiter) ScrollDir(amount int, direction string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.driver.ScrollDir(amount, direction)
+}
+
+func (a *inputArbiter) KeyTap(key string, modifiers ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.KeyTap(key, modifiers...)
+}
+
+func (a *inputArbiter) KeyDown(key string, modifiers ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.KeyDown(key, modifiers...)
+}
+
+func (a *inputArbiter) KeyUp(key string, modifiers ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.KeyUp(key, modifiers...)
+}
+
+func (a *inputArbiter) TypeStr(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.driver.TypeStr(text)
+}
+
+func (a *inputArbiter) Location() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.Location()
+}
+
+func (a *inputArbiter) PixelColor(x, y int) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.PixelColor(x, y)
+}
+
+// MoveWithSpeed holds the lock from setting the speed to restoring it.
+func (a *inputArbiter) MoveWithSpeed(m MouseMotion) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.driver.MoveWithSpeed(m)
+}
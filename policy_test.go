@@ -0,0 +1,38 @@
+// policy_test.go
+
+package main
+
+import "testing"
+
+func TestSequentialPolicyOrder(t *testing.T) {
+	// Two branches of two nodes each, joined at the end
+	flow := graph([]testNode{
+		node("a", "TypeString", `{"text":"a"}`),
+		node("a2", "TypeString", `{"text":"a2"}`),
+		node("b", "TypeString", `{"text":"b"}`),
+		node("b2", "TypeString", `{"text":"b2"}`),
+		node("join", "TypeString", `{"text":"join"}`),
+	}, "start->a", "start->b", "a->a2", "b->b2", "a2->join", "b2->join")
+
+	tests := []struct {
+		name    string
+		options string
+		want    []string
+	}{
+		{"default", `{}`, []string{"type(a)", "type(a2)", "type(b)", "type(b2)", "type(join)"}},
+		{"sequential-dfs", `{"policy":"sequential-dfs"}`, []string{"type(a)", "type(a2)", "type(b)", "type(b2)", "type(join)"}},
+		{"sequential-bfs", `{"policy":"sequential-bfs"}`, []string{"type(a)", "type(b)", "type(a2)", "type(b2)", "type(join)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertActions(t, runFlow(t, withOptions(flow, tt.options)), tt.want...)
+		})
+	}
+}
+
+func TestUnknownPolicy(t *testing.T) {
+	app := NewAppWithDriver(NewRecordingDriver())
+	if _, err := app.StartExecution(withOptions(chain(), `{"policy":"random"}`)); err == nil {
+		t.Error("a run with an unknown policy started")
+	}
+}
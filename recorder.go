@@ -0,0 +1,455 @@
+// recorder.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InputEventKind is the kind of a recorded input event.
+type InputEventKind string
+
+const (
+	EventMove      InputEventKind = "move"
+	EventMouseDown InputEventKind = "mouseDown"
+	EventMouseUp   InputEventKind = "mouseUp"
+	EventScroll    InputEventKind = "scroll"
+	EventKeyDown   InputEventKind = "keyDown"
+	EventKeyUp     InputEventKind = "keyUp"
+)
+
+// InputEvent is a global mouse or keyboard event captured while recording.
+type InputEvent struct {
+	Kind InputEventKind
+	Time time.Time
+	// X and Y are the cursor position for mouse events.
+	X, Y int
+	// Button is "left", "right" or "middle" for mouseDown and mouseUp.
+	Button string
+	// ScrollX and ScrollY are the lines scrolled, positive right and down.
+	ScrollX, ScrollY int
+	// Key is the robotgo name of the key, e.g. "a", "enter" or "ctrl".
+	// Modifiers are always reported as "ctrl", "alt", "shift" or "cmd".
+	Key string
+	// Text is the printable text a keyDown produces, e.g. "A" with shift
+	// held, or empty for keys such as enter or arrows.
+	Text string
+}
+
+// HookSource delivers global input events, e.g. to the recorder and the
+// hotkey listener.
+type HookSource interface {
+	// Subscribe begins capturing. Events arrive on the returned channel until
+	// cancel is called, which closes it. Each subscriber gets every event.
+	Subscribe() (events <-chan InputEvent, cancel func(), err error)
+}
+
+// errHooksUnsupported is returned where there is no HookSource.
+var errHooksUnsupported = errors.New("global input hooks are not supported on this platform")
+
+//=============================================== Fake Hook ===============================================
+
+// FakeHookSource is a HookSource fed by hand, for recording and hotkeys
+// without a desktop.
+type FakeHookSource struct {
+	mu          sync.Mutex
+	subscribers map[chan InputEvent]bool
+}
+
+// NewFakeHookSource creates a FakeHookSource.
+func NewFakeHookSource() *FakeHookSource {
+	return &FakeHookSource{subscribers: make(map[chan InputEvent]bool)}
+}
+
+func (f *FakeHookSource) Subscribe() (<-chan InputEvent, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan InputEvent, 1024)
+	f.subscribers[ch] = true
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.subscribers[ch] {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, nil
+}
+
+// Send delivers events to every subscriber as if they had happened.
+func (f *FakeHookSource) Send(events ...InputEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		for _, e := range events {
+			ch <- e
+		}
+	}
+}
+
+//=============================================== Recording ===============================================
+
+// recording collects events from a HookSource until it is stopped.
+type recording struct {
+	cancel  func()
+	events  []InputEvent
+	hotkeys map[string][]keyChord // left out of the recorded flow
+	done    chan struct{}
+}
+
+func startRecording(hooks HookSource, hotkeys map[string][]keyChord) (*recording, error) {
+	ch, cancel, err := hooks.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	r := &recording{cancel: cancel, hotkeys: hotkeys, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		for e := range ch {
+			r.events = append(r.events, e)
+		}
+	}()
+	return r, nil
+}
+
+// stop ends the recording and returns every event captured.
+func (r *recording) stop() []InputEvent {
+	r.cancel()
+	<-r.done
+	return r.events
+}
+
+//=============================================== Flow Building ===============================================
+
+const (
+	// recordMinDelay is the shortest pause between actions kept as a DelayNode.
+	recordMinDelay = 100 * time.Millisecond
+	// recordTypingPause splits typed text into separate nodes.
+	recordTypingPause = time.Second
+	// recordMultiClick is the longest gap between clicks merged into one node.
+	recordMultiClick = 500 * time.Millisecond
+	// recordDragDistance is how far the mouse must move while held to drag.
+	recordDragDistance = 5
+
+	// Recorded nodes are laid out left to right, wrapping into rows.
+	recordLayoutColumns = 6
+	recordLayoutX       = 250
+	recordLayoutY       = 200
+)
+
+// recordModifiers are the modifier keys in the order KeyTap receives them.
+var recordModifiers = []string{"ctrl", "alt", "shift", "cmd"}
+
+func isModifier(key string) bool {
+	for _, m := range recordModifiers {
+		if key == m {
+			return true
+		}
+	}
+	return false
+}
+
+// flowBuilder turns recorded events into a chain of nodes.
+type flowBuilder struct {
+	flow   FlowData
+	lastAt time.Time // when the previous action happened
+
+	// Where the last node left the cursor
+	cursorKnown bool
+	cursorX     int
+	cursorY     int
+
+	down     *InputEvent     // unreleased mouse button
+	click    *InputEvent     // mouse up that ended the last click node
+	text     strings.Builder // text not yet put in a TypeString node
+	held     map[string]bool // modifier -> used with another key
+	lastNode map[string]interface{}
+}
+
+// buildRecordedFlow converts events into a FlowData starting with a
+// StartNode: clicks, drags and scrolls become mouse nodes, typing becomes
+// TypeString and other keys KeyTap, with DelayNodes for the pauses between.
+// The path the cursor took between actions is dropped; it moves straight to
+// where each click happened. Presses that complete a hotkey are left out,
+// as are keys robotgo can't press.
+func buildRecordedFlow(events []InputEvent, hotkeys map[string][]keyChord) FlowData {
+	b := &flowBuilder{held: make(map[string]bool)}
+	b.add("StartNode", map[string]interface{}{})
+	if len(events) > 0 {
+		b.lastAt = events[0].Time
+	}
+	skip := hotkeyPresses(events, hotkeys)
+	for i, e := range events {
+		b.handle(e, skip[i])
+	}
+	b.flushText()
+	return b.flow
+}
+
+// hotkeyPresses returns the positions of the key presses that complete a
+// hotkey, matched as the hotkey listener matches them. The start of a
+// sequence that is never finished, such as a single esc of "esc esc", is
+// real input and isn't included.
+func hotkeyPresses(events []InputEvent, hotkeys map[string][]keyChord) map[int]bool {
+	m := newHotkeyMatcher(hotkeys)
+	out := make(map[int]bool)
+	for _, e := range events {
+		for _, match := range m.feed(e) {
+			for _, pos := range match.presses {
+				out[pos] = true
+			}
+		}
+	}
+	return out
+}
+
+// handle adds the nodes for e. A hotkey press only counts as using the
+// modifiers held with it.
+func (b *flowBuilder) handle(e InputEvent, hotkey bool) {
+	switch e.Kind {
+	case EventMouseDown:
+		if b.down == nil {
+			b.down = &e
+		}
+
+	case EventMouseUp:
+		if b.down == nil || b.down.Button != e.Button {
+			return
+		}
+		down := *b.down
+		b.down = nil
+		b.flushText()
+		if down.Button == "left" && distance(down.X, down.Y, e.X, e.Y) > recordDragDistance {
+			b.drag(down, e)
+		} else {
+			b.mouseClick(down, e)
+		}
+
+	case EventScroll:
+		b.flushText()
+		b.scroll(e)
+
+	case EventKeyDown:
+		if isModifier(e.Key) {
+			if _, ok := b.held[e.Key]; !ok {
+				b.held[e.Key] = false
+			}
+			return
+		}
+		for m := range b.held {
+			b.held[m] = true
+		}
+		if hotkey {
+			return
+		}
+		if e.Text != "" && !b.chording() {
+			b.typeText(e)
+			return
+		}
+		if _, err := parseKey(e.Key); err != nil {
+			// e.g. pause, which the hook reports but robotgo can't press
+			return
+		}
+		b.flushText()
+		b.wait(e.Time)
+		data := map[string]interface{}{"key": e.Key}
+		if mods := b.modifiers(); len(mods) > 0 {
+			data["modifiers"] = mods
+		}
+		b.add("KeyTap", data)
+		b.lastAt = e.Time
+
+	case EventKeyUp:
+		used, ok := b.held[e.Key]
+		if !ok {
+			return
+		}
+		delete(b.held, e.Key)
+		// A modifier pressed on its own, e.g. the Windows key
+		if !used {
+			b.flushText()
+			b.wait(e.Time)
+			b.add("KeyTap", map[string]interface{}{"key": e.Key})
+			b.lastAt = e.Time
+		}
+	}
+}
+
+// chording reports whether a modifier other than shift is held, so keys
+// are shortcuts rather than text.
+func (b *flowBuilder) chording() bool {
+	for m := range b.held {
+		if m != "shift" {
+			return true
+		}
+	}
+	return false
+}
+
+// modifiers returns the held modifiers in a stable order.
+func (b *flowBuilder) modifiers() []interface{} {
+	var out []interface{}
+	for _, m := range recordModifiers {
+		if _, ok := b.held[m]; ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (b *flowBuilder) typeText(e InputEvent) {
+	if b.text.Len() > 0 && e.Time.Sub(b.lastAt) >= recordTypingPause {
+		b.flushText()
+	}
+	if b.text.Len() == 0 {
+		b.wait(e.Time)
+	}
+	b.text.WriteString(e.Text)
+	b.lastAt = e.Time
+}
+
+func (b *flowBuilder) flushText() {
+	if b.text.Len() == 0 {
+		return
+	}
+	b.add("TypeString", map[string]interface{}{"text": b.text.String()})
+	b.text.Reset()
+}
+
+func (b *flowBuilder) mouseClick(down, up InputEvent) {
+	// Clicks in quick succession on the same spot are a double click
+	if b.click != nil && b.isLast("MouseClickNode") && b.click.Button == up.Button &&
+		down.Time.Sub(b.click.Time) <= recordMultiClick &&
+		distance(b.click.X, b.click.Y, down.X, down.Y) <= recordDragDistance {
+		b.lastNode["numberOfClicks"] = b.lastNode["numberOfClicks"].(int) + 1
+		b.lastNode["clickDelay"] = float64(down.Time.Sub(b.click.Time).Milliseconds())
+		b.click = &up
+		b.lastAt = up.Time
+		return
+	}
+
+	b.wait(down.Time)
+	b.moveTo(down.X, down.Y)
+	b.add("MouseClickNode", map[string]interface{}{
+		"buttonType":     down.Button,
+		"numberOfClicks": 1,
+	})
+	b.click = &up
+	b.lastAt = up.Time
+}
+
+func (b *flowBuilder) drag(down, up InputEvent) {
+	b.wait(down.Time)
+	b.add("MouseMoveNode", map[string]interface{}{
+		"startPosition":   fixedPosition(down.X, down.Y),
+		"endPosition":     fixedPosition(up.X, up.Y),
+		"dragWhileMoving": true,
+		"speed":           map[string]interface{}{"type": "Human"},
+		"pathType":        "Straight",
+	})
+	b.cursorKnown, b.cursorX, b.cursorY = true, up.X, up.Y
+	b.lastAt = up.Time
+}
+
+func (b *flowBuilder) scroll(e InputEvent) {
+	direction, lines := "Vertical", e.ScrollY
+	if e.ScrollX != 0 {
+		direction, lines = "Horizontal", e.ScrollX
+	}
+	if lines == 0 {
+		return
+	}
+
+	// Consecutive wheel notches in the same direction make one node
+	if b.isLast("MouseClickNode") && b.lastNode["numberOfClicks"] == 0 &&
+		b.lastNode["scrollDirection"].([]interface{})[0] == direction &&
+		(b.lastNode["scrollLines"].(int) > 0) == (lines > 0) &&
+		e.Time.Sub(b.lastAt) < recordMultiClick &&
+		b.cursorX == e.X && b.cursorY == e.Y {
+		b.lastNode["scrollLines"] = b.lastNode["scrollLines"].(int) + lines
+		b.lastAt = e.Time
+		return
+	}
+
+	b.wait(e.Time)
+	b.moveTo(e.X, e.Y)
+	b.add("MouseClickNode", map[string]interface{}{
+		"numberOfClicks":  0,
+		"scrollDirection": []interface{}{direction},
+		"scrollLines":     lines,
+	})
+	b.lastAt = e.Time
+}
+
+// moveTo adds a MouseMoveNode unless the cursor is already at x, y.
+func (b *flowBuilder) moveTo(x, y int) {
+	if b.cursorKnown && b.cursorX == x && b.cursorY == y {
+		return
+	}
+	b.add("MouseMoveNode", map[string]interface{}{
+		"startPosition": map[string]interface{}{"type": "Mouse"},
+		"endPosition":   fixedPosition(x, y),
+		"speed":         map[string]interface{}{"type": "Instant"},
+		"pathType":      "Straight",
+	})
+	b.cursorKnown, b.cursorX, b.cursorY = true, x, y
+}
+
+// wait adds a DelayNode for the time since the previous action, if it is
+// long enough to matter.
+func (b *flowBuilder) wait(at time.Time) {
+	gap := at.Sub(b.lastAt)
+	if gap < recordMinDelay {
+		return
+	}
+	b.add("DelayNode", map[string]interface{}{
+		"delayType": "Fixed",
+		"time":      float64(gap.Milliseconds()),
+	})
+}
+
+// add appends a node to the chain, connected to the previous one.
+func (b *flowBuilder) add(nodeType string, data map[string]interface{}) {
+	i := len(b.flow.Nodes)
+	id := fmt.Sprintf("rec-%d", i)
+	b.flow.Nodes = append(b.flow.Nodes, Node{
+		ID:   id,
+		Type: nodeType,
+		Data: data,
+		Position: map[string]float64{
+			"x": float64(i%recordLayoutColumns) * recordLayoutX,
+			"y": float64(i/recordLayoutColumns) * recordLayoutY,
+		},
+	})
+	if i > 0 {
+		b.flow.Edges = append(b.flow.Edges, Edge{
+			ID:     fmt.Sprintf("rec-e%d", i),
+			Source: b.flow.Nodes[i-1].ID,
+			Target: id,
+		})
+	}
+	b.lastNode = data
+}
+
+// isLast reports whether the most recent node has type nodeType.
+func (b *flowBuilder) isLast(nodeType string) bool {
+	n := len(b.flow.Nodes)
+	return n > 0 && b.flow.Nodes[n-1].Type == nodeType
+}
+
+func fixedPosition(x, y int) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "Fixed",
+		"coordinates": map[string]interface{}{"x": float64(x), "y": float64(y)},
+	}
+}
+
+func distance(x1, y1, x2, y2 int) float64 {
+	return math.Hypot(float64(x2-x1), float64(y2-y1))
+}
@@ -0,0 +1,151 @@
+// recorder_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// inputScript builds the timed events of a FakeHookSource.
+type inputScript struct {
+	start  time.Time
+	events []InputEvent
+}
+
+func newInputScript() *inputScript {
+	return &inputScript{start: time.Now()}
+}
+
+func (s *inputScript) at(ms int) time.Time {
+	return s.start.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func (s *inputScript) click(ms, x, y int) {
+	s.events = append(s.events,
+		InputEvent{Kind: EventMouseDown, Time: s.at(ms), X: x, Y: y, Button: "left"},
+		InputEvent{Kind: EventMouseUp, Time: s.at(ms + 60), X: x, Y: y, Button: "left"})
+}
+
+// press presses and releases key with modifiers held, typing text.
+func (s *inputScript) press(ms int, key, text string, modifiers ...string) {
+	for _, m := range modifiers {
+		s.events = append(s.events, InputEvent{Kind: EventKeyDown, Time: s.at(ms), Key: m})
+	}
+	s.events = append(s.events,
+		InputEvent{Kind: EventKeyDown, Time: s.at(ms + 10), Key: key, Text: text},
+		InputEvent{Kind: EventKeyUp, Time: s.at(ms + 40), Key: key})
+	for _, m := range modifiers {
+		s.events = append(s.events, InputEvent{Kind: EventKeyUp, Time: s.at(ms + 50), Key: m})
+	}
+}
+
+// nodeSummaries describes each node by its type and data.
+func nodeSummaries(t *testing.T, flow FlowData) []string {
+	t.Helper()
+	out := make([]string, len(flow.Nodes))
+	for i, n := range flow.Nodes {
+		data, err := json.Marshal(n.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = n.Type + " " + string(data)
+	}
+	return out
+}
+
+func TestRecording(t *testing.T) {
+	hooks := NewFakeHookSource()
+	app := NewAppWithDriver(NewRecordingDriver())
+	app.hooks = hooks
+
+	s := newInputScript()
+	s.events = append(s.events, InputEvent{Kind: EventMove, Time: s.at(0), X: 5, Y: 5})
+	s.click(500, 100, 200)
+	s.press(1000, "h", "h")
+	s.press(1100, "i", "i")
+	s.press(1500, "s", "s", "ctrl")
+	// Pause is the pause hotkey and can't be pressed by robotgo anyway
+	s.press(2000, "pause", "")
+	// The stop and start hotkeys
+	s.press(2500, "esc", "")
+	s.press(2600, "esc", "")
+	s.press(3000, "s", "s", "ctrl", "alt")
+	s.press(3500, "enter", "")
+
+	if err := app.StartRecording(); err != nil {
+		t.Fatal(err)
+	}
+	hooks.Send(s.events...)
+	flow, err := app.StopRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertActions(t, nodeSummaries(t, flow),
+		`StartNode {}`,
+		`DelayNode {"delayType":"Fixed","time":500}`,
+		`MouseMoveNode {"endPosition":{"coordinates":{"x":100,"y":200},"type":"Fixed"},"pathType":"Straight","speed":{"type":"Instant"},"startPosition":{"type":"Mouse"}}`,
+		`MouseClickNode {"buttonType":"left","numberOfClicks":1}`,
+		`DelayNode {"delayType":"Fixed","time":450}`,
+		`TypeString {"text":"hi"}`,
+		`DelayNode {"delayType":"Fixed","time":400}`,
+		`KeyTap {"key":"s","modifiers":["ctrl"]}`,
+		`DelayNode {"delayType":"Fixed","time":2000}`,
+		`KeyTap {"key":"enter"}`,
+	)
+	if report := validateFlow(flow); !report.Valid {
+		t.Errorf("recorded flow is invalid: %v", report.Err())
+	}
+}
+
+func TestRecordingModifierAlone(t *testing.T) {
+	s := newInputScript()
+	s.events = append(s.events,
+		InputEvent{Kind: EventKeyDown, Time: s.at(0), Key: "cmd"},
+		InputEvent{Kind: EventKeyUp, Time: s.at(50), Key: "cmd"})
+	flow := buildRecordedFlow(s.events, nil)
+	assertActions(t, nodeSummaries(t, flow), `StartNode {}`, `KeyTap {"key":"cmd"}`)
+}
+
+func TestRecordingHotkeySequence(t *testing.T) {
+	s := newInputScript()
+	s.press(0, "esc", "")
+	// The stop hotkey, esc esc
+	s.press(1000, "esc", "")
+	s.press(1200, "esc", "")
+	// A sequence that isn't finished in time
+	s.press(2000, "esc", "")
+	s.press(2600, "esc", "")
+	// A sequence broken by another key
+	s.press(4000, "esc", "")
+	s.press(4100, "x", "x")
+	flow := buildRecordedFlow(s.events, defaultSettings().Hotkeys.sequences())
+	assertActions(t, nodeSummaries(t, flow),
+		`StartNode {}`,
+		`KeyTap {"key":"esc"}`,
+		`DelayNode {"delayType":"Fixed","time":2000}`,
+		`KeyTap {"key":"esc"}`,
+		`DelayNode {"delayType":"Fixed","time":600}`,
+		`KeyTap {"key":"esc"}`,
+		`DelayNode {"delayType":"Fixed","time":1400}`,
+		`KeyTap {"key":"esc"}`,
+		`DelayNode {"delayType":"Fixed","time":100}`,
+		`TypeString {"text":"x"}`,
+	)
+}
+
+func TestRecordingUnsupported(t *testing.T) {
+	app := NewAppWithDriver(NewRecordingDriver())
+	if app.GetInputHooksSupported() {
+		t.Error("hooks reported as supported without a HookSource")
+	}
+	if err := app.StartRecording(); !errors.Is(err, errHooksUnsupported) {
+		t.Errorf("StartRecording: got %v, want %v", err, errHooksUnsupported)
+	}
+	if _, err := app.StopRecording(); err == nil {
+		t.Error("StopRecording succeeded without a recording")
+	}
+}
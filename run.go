@@ -0,0 +1,828 @@
+// run.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ExecutionRun is a single execution of a flowchart. Every StartExecution
+// creates a fresh run with its own context, task queue and scheduling state,
+// so stopping one run never affects the next. A CallFlowNode runs its flow as
+// a sub-run, which shares the input, pause gate and debugger of its parent.
+type ExecutionRun struct {
+	ID      string
+	parent  *ExecutionRun // nil for the run started by StartExecution
+	path    []string      // IDs of the CallFlowNodes a sub-run was called through
+	ended   chan struct{} // closed when a sub-run ends on its own
+	outcome error         // why a sub-run failed, once ended is closed
+	app     *App
+	options RunOptions
+	driver  InputDriver
+	ctx     context.Context
+	cancel  context.CancelFunc
+	queue   *TaskQueue
+	gate    *pauseGate
+	debug   *debugger
+	vars    *variableStore
+	held    *heldInput
+	pointer *pointerTracker // nil unless the fail-safe is enabled
+	safety  SafetySettings
+
+	timeouts TimeoutSettings
+	timedOut chan struct{} // closed when the run runs out of time
+
+	startNode Node
+	excluded  []string
+	nodeMap   map[string]Node
+	edges     []Edge                     // edges between nodes that will run
+	outgoing  map[string][]int           // node ID -> indexes into edges
+	incoming  map[string][]int           // node ID -> indexes into edges
+	backEdge  []bool                     // edge returns from a loop body to its LoopNode
+	loops     map[string]map[string]bool // LoopNode ID -> body node IDs
+	loopIDs   []string                   // sorted keys of loops
+	innermost map[string]string          // node ID -> innermost LoopNode whose body holds it
+	notifyCh  chan taskResult
+
+	stateMu   sync.Mutex
+	nodeState map[string]map[string]interface{}
+
+	resultsMu  sync.Mutex
+	results    map[string]nodeResult
+	resultsSeq int
+
+	// Scheduling state, owned by the handleCompletions goroutine
+	ready      frontier
+	status     map[string]nodeStatus
+	edgeStatus []edgeStatus
+	inFlight   int
+}
+
+// nodeStatus tracks a node through a run. Nodes inside a loop body go back
+// to pending at the start of every iteration.
+type nodeStatus int
+
+const (
+	nodePending   nodeStatus = iota
+	nodeScheduled            // queued or running
+	nodeLooping              // a LoopNode whose body is running
+	nodeDone
+	nodeSkipped
+)
+
+func (s nodeStatus) settled() bool {
+	return s == nodeDone || s == nodeSkipped
+}
+
+// edgeStatus records whether an edge has run. A node becomes ready once none
+// of its incoming edges are pending, and is skipped if none of them fired.
+type edgeStatus int
+
+const (
+	edgePending edgeStatus = iota
+	edgeFired
+	edgeSkipped
+)
+
+// nodeResult is the latest outcome of a node, for conditions that test it.
+type nodeResult struct {
+	Status string // "success", "error" or "skipped"
+	Error  string
+	seq    int // order in which results were recorded
+}
+
+// taskResult is what a worker reports to the scheduler when a task ends.
+type taskResult struct {
+	taskID   string
+	handles  []string // output handles selected with ExecContext.Follow
+	err      error
+	attempts int
+}
+
+// TaskQueue manages the queue of tasks to be executed.
+type TaskQueue struct {
+	tasks   chan Task
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	mutex   sync.Mutex
+	run     *ExecutionRun
+}
+
+// newExecutionRun prepares a run of flowchart. The flowchart must already
+// have passed validation.
+func newExecutionRun(app *App, flowchart Flowchart, options RunOptions) (*ExecutionRun, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ExecutionRun{
+		ID:       uuid.NewString(),
+		app:      app,
+		options:  options,
+		driver:   app.driver,
+		ctx:      ctx,
+		cancel:   cancel,
+		gate:     newPauseGate(),
+		debug:    newDebugger(options.Debug),
+		vars:     newVariableStore(),
+		timedOut: make(chan struct{}),
+	}
+	settings := app.GetSettings()
+	r.timeouts = settings.Timeouts
+
+	// The fail-safe needs to know where the flow put the cursor
+	if r.safety = settings.Safety; r.safety.Enabled && (r.safety.Corners || r.safety.Interference) {
+		r.pointer = newPointerTracker(r.driver)
+		r.driver = r.pointer
+	}
+
+	// Parallel branches share one mouse and keyboard, so serialise their input
+	if !options.sequential() {
+		r.driver = newInputArbiter(r.driver)
+	}
+	r.held = newHeldInput(r.driver)
+	r.driver = r.held
+
+	if err := r.load(flowchart.Nodes, flowchart.Edges); err != nil {
+		cancel()
+		return nil, err
+	}
+	r.startQueue()
+	log.Printf("Execution %s prepared with policy %s", r.ID, options.Policy)
+	return r, nil
+}
+
+// newSubRun prepares a run of flow for the CallFlowNode nodeID, inside r.
+// It stops when ctx is done and starts with vars as its variables.
+func (r *ExecutionRun) newSubRun(ctx context.Context, nodeID string, flow FlowData, vars *variableStore) (*ExecutionRun, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	options := r.options
+	options.Timeout = 0
+	sub := &ExecutionRun{
+		ID:       r.ID,
+		parent:   r,
+		path:     append(slices.Clone(r.path), nodeID),
+		ended:    make(chan struct{}),
+		app:      r.app,
+		options:  options,
+		driver:   r.driver,
+		ctx:      ctx,
+		cancel:   cancel,
+		gate:     r.gate,
+		debug:    r.debug,
+		vars:     vars,
+		held:     r.held,
+		timeouts: r.timeouts,
+		timedOut: make(chan struct{}),
+	}
+	if err := sub.load(flow.Nodes, flow.Edges); err != nil {
+		cancel()
+		return nil, err
+	}
+	sub.startQueue()
+	return sub, nil
+}
+
+// load indexes the nodes and edges the run will execute and resets its
+// scheduling state.
+func (r *ExecutionRun) load(nodes []Node, edges []Edge) error {
+	r.nodeMap = make(map[string]Node)
+	r.outgoing = make(map[string][]int)
+	r.incoming = make(map[string][]int)
+	r.loops = make(map[string]map[string]bool)
+	r.innermost = make(map[string]string)
+	r.notifyCh = make(chan taskResult, 100)
+	r.nodeState = make(map[string]map[string]interface{})
+	r.results = make(map[string]nodeResult)
+	r.ready = frontier{policy: r.options.Policy}
+	r.status = make(map[string]nodeStatus)
+
+	startNode, err := findStartNode(nodes)
+	if err != nil {
+		return err
+	}
+	r.startNode = startNode
+
+	// Only nodes reachable from the StartNode will run, so they alone decide
+	// when execution is complete
+	adjacency := make(map[string][]string)
+	for _, edge := range edges {
+		adjacency[edge.Source] = append(adjacency[edge.Source], edge.Target)
+	}
+	reachable := reachableFrom(startNode.ID, adjacency)
+
+	for _, node := range nodes {
+		if reachable[node.ID] {
+			r.nodeMap[node.ID] = node
+		} else {
+			r.excluded = append(r.excluded, node.ID)
+		}
+	}
+
+	// Index the edges between the nodes that will run. Validation has
+	// already rejected edges to missing nodes, and the targets of a reachable
+	// source are reachable themselves.
+	for _, edge := range edges {
+		if _, runs := r.nodeMap[edge.Source]; !runs {
+			continue
+		}
+		i := len(r.edges)
+		r.edges = append(r.edges, edge)
+		r.outgoing[edge.Source] = append(r.outgoing[edge.Source], i)
+		r.incoming[edge.Target] = append(r.incoming[edge.Target], i)
+	}
+	r.edgeStatus = make([]edgeStatus, len(r.edges))
+
+	// Edges from a loop body back to its LoopNode don't gate the loop; it is
+	// re-run by the scheduler once the body has settled
+	r.backEdge = make([]bool, len(r.edges))
+	for id, node := range r.nodeMap {
+		if node.Type != "LoopNode" {
+			continue
+		}
+		body := loopBranch(id, HandleLoopBody, r.edges)
+		r.loops[id] = body
+		r.loopIDs = append(r.loopIDs, id)
+		for _, i := range r.incoming[id] {
+			if body[r.edges[i].Source] {
+				r.backEdge[i] = true
+			}
+		}
+	}
+	sort.Strings(r.loopIDs)
+
+	// Loops nest, so the innermost loop around a node has the smallest body
+	for _, id := range r.loopIDs {
+		for bodyID := range r.loops[id] {
+			if inner, ok := r.innermost[bodyID]; !ok || len(r.loops[id]) < len(r.loops[inner]) {
+				r.innermost[bodyID] = id
+			}
+		}
+	}
+	return nil
+}
+
+// startQueue starts the workers that run the tasks the scheduler dispatches.
+func (r *ExecutionRun) startQueue() {
+	workers := 3
+	if r.options.sequential() {
+		workers = 1
+	}
+	r.queue = NewTaskQueue(r, 100)
+	r.queue.Start(workers)
+}
+
+// findStartNode locates the StartNode in the flowchart.
+func findStartNode(nodes []Node) (Node, error) {
+	for _, node := range nodes {
+		if node.Type == "StartNode" {
+			return node, nil
+		}
+	}
+	return Node{}, errors.New("no Start node found in flowchart")
+}
+
+// emit sends a run event to the frontend. The payload is tagged with the
+// run ID so the UI can ignore events from runs it no longer cares about, and
+// events from sub-runs with the path of CallFlowNodes they came through.
+func (r *ExecutionRun) emit(event string, payload map[string]interface{}) {
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+	payload["runId"] = r.ID
+	if r.parent != nil {
+		payload["path"] = r.path
+	}
+	r.app.emitEvent(event, payload)
+}
+
+// start enqueues the StartNode and begins handling completions.
+func (r *ExecutionRun) start() {
+	if r.parent == nil {
+		r.emit("execution-started", nil)
+	}
+	if len(r.excluded) > 0 {
+		log.Printf("Excluding %d node(s) not reachable from the Start node: %v", len(r.excluded), r.excluded)
+		r.emit("execution-warning", map[string]interface{}{
+			"message": fmt.Sprintf("%d node(s) are not reachable from the Start node and will not run", len(r.excluded)),
+			"nodeIds": r.excluded,
+		})
+	}
+	r.status[r.startNode.ID] = nodeScheduled
+	r.ready.push(taskForNode(r.startNode))
+	r.dispatch()
+
+	if r.pointer != nil {
+		go r.monitorSafety(r.safety, r.pointer)
+	}
+	if timeout := r.runTimeout(); timeout > 0 && r.parent == nil {
+		go r.timeRun(timeout)
+	}
+	// Start a goroutine to handle task completions
+	go r.handleCompletions()
+}
+
+// stop cancels the run, waits for its workers to exit and returns the keys
+// and buttons it had to release.
+func (r *ExecutionRun) stop() []string {
+	r.cancel()
+	r.queue.Stop()
+	return r.releaseHeld()
+}
+
+// releaseHeld lets go of every key and button the flow is still holding once
+// the run is over. Keys held in a called flow stay down for its caller.
+func (r *ExecutionRun) releaseHeld() []string {
+	if r.parent != nil {
+		return nil
+	}
+	released := r.held.releaseAll()
+	if len(released) > 0 {
+		log.Printf("Execution %s released %v", r.ID, released)
+	}
+	return released
+}
+
+// pause suspends the run between nodes and interrupts sleeping executors.
+func (r *ExecutionRun) pause() bool {
+	if !r.gate.Pause() {
+		return false
+	}
+	log.Printf("Execution %s paused", r.ID)
+	r.emit("execution-paused", nil)
+	return true
+}
+
+// resume continues a paused run.
+func (r *ExecutionRun) resume() bool {
+	if !r.gate.Resume() {
+		return false
+	}
+	log.Printf("Execution %s resumed", r.ID)
+	r.emit("execution-resumed", nil)
+	return true
+}
+
+// taskForNode builds the task that executes node.
+func taskForNode(node Node) Task {
+	return Task{
+		ID:   node.ID,
+		Type: node.Type,
+		Data: node.Data,
+	}
+}
+
+// nodeStateFor returns the persistent state of a node, creating it on first use.
+func (r *ExecutionRun) nodeStateFor(nodeID string) map[string]interface{} {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	state, ok := r.nodeState[nodeID]
+	if !ok {
+		state = make(map[string]interface{})
+		r.nodeState[nodeID] = state
+	}
+	return state
+}
+
+// iteration returns the current iteration of the innermost loop around
+// nodeID, or 0 outside loops.
+func (r *ExecutionRun) iteration(nodeID string) int {
+	loopID, ok := r.innermost[nodeID]
+	if !ok {
+		return 0
+	}
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+	n, _ := r.nodeState[loopID]["iteration"].(int)
+	return n
+}
+
+// recordResult stores the outcome of a node.
+func (r *ExecutionRun) recordResult(nodeID, status string, err error) {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	r.resultsSeq++
+	result := nodeResult{Status: status, seq: r.resultsSeq}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.results[nodeID] = result
+}
+
+// result returns the latest outcome of a node, if it has finished.
+func (r *ExecutionRun) result(nodeID string) (nodeResult, bool) {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	result, ok := r.results[nodeID]
+	return result, ok
+}
+
+// previousNode returns the node feeding into nodeID that finished last.
+func (r *ExecutionRun) previousNode(nodeID string) (string, bool) {
+	r.resultsMu.Lock()
+	defer r.resultsMu.Unlock()
+	var prev string
+	latest := 0
+	for _, i := range r.incoming[nodeID] {
+		source := r.edges[i].Source
+		if result, ok := r.results[source]; ok && result.seq > latest {
+			prev, latest = source, result.seq
+		}
+	}
+	return prev, prev != ""
+}
+
+// runTask executes a single task on a worker, retrying it as its error
+// policy allows, and reports its outcome. What a failure does to the rest of
+// the run is up to the scheduler.
+func (r *ExecutionRun) runTask(task Task) {
+	policy := nodeErrorPolicy(r.nodeMap[task.ID])
+	timeout := r.nodeTimeout(r.nodeMap[task.ID])
+	// Stop at a breakpoint once, not again before every retry. The node only
+	// starts once the debugger lets it go.
+	if err := r.breakBefore(newExecContext(task, r)); err != nil {
+		log.Printf("Task %s interrupted: %v", task.ID, err)
+		return
+	}
+	r.emit("task-started", map[string]interface{}{"taskID": task.ID})
+	attempt := func() ([]string, error) {
+		handles, err := executeTask(task, r, timeout)
+		if errors.Is(err, errTaskTimeout) && r.ctx.Err() == nil {
+			log.Printf("Task %s timed out after %v", task.ID, timeout)
+			r.emit("task-timed-out", map[string]interface{}{
+				"taskID":  task.ID,
+				"type":    task.Type,
+				"timeout": timeout.Milliseconds(),
+			})
+		}
+		return handles, err
+	}
+	handles, err := attempt()
+	attempts := 1
+	for err != nil && attempts <= policy.Retries && r.ctx.Err() == nil {
+		delay := policy.delay(attempts)
+		log.Printf("Task %s failed: %v; retry %d of %d in %v", task.ID, err, attempts, policy.Retries, delay)
+		r.emit("task-retry", map[string]interface{}{
+			"taskID":  task.ID,
+			"error":   err.Error(),
+			"attempt": attempts,
+			"retries": policy.Retries,
+			"delay":   delay.Milliseconds(),
+		})
+		// Time paused doesn't count towards the delay
+		if r.gate.Sleep(r.ctx, delay) != nil {
+			break
+		}
+		handles, err = attempt()
+		attempts++
+	}
+	if r.ctx.Err() != nil {
+		// The run was stopped; the interrupted task has nothing more to report
+		log.Printf("Task %s interrupted: execution %s stopped", task.ID, r.ID)
+		return
+	}
+	if err != nil {
+		log.Printf("Task %s failed after %d attempt(s): %v (onError: %s)", task.ID, attempts, err, policy.Action)
+		handles = nil
+		r.recordResult(task.ID, "error", err)
+		r.emit("task-error", map[string]interface{}{
+			"taskID":   task.ID,
+			"error":    err.Error(),
+			"attempts": attempts,
+			"action":   policy.Action,
+		})
+	} else {
+		r.recordResult(task.ID, "success", nil)
+		r.emit("task-success", map[string]interface{}{
+			"taskID": task.ID,
+			"type":   task.Type,
+		})
+		r.emit("task-completed", map[string]interface{}{"taskID": task.ID})
+	}
+	// Notify task completion for dependency handling
+	r.notifyTaskCompletion(taskResult{taskID: task.ID, handles: handles, err: err, attempts: attempts})
+}
+
+// notifyTaskCompletion is called by TaskQueue workers when a task is
+// completed. The scheduler can't finish without it, so it waits for room in
+// the channel unless the run is stopped.
+func (r *ExecutionRun) notifyTaskCompletion(result taskResult) {
+	select {
+	case r.notifyCh <- result:
+		log.Printf("Task %s completion notified", result.taskID)
+	case <-r.ctx.Done():
+	}
+}
+
+// handleCompletions listens for completed tasks and enqueues dependent tasks.
+func (r *ExecutionRun) handleCompletions() {
+	for {
+		select {
+		case result := <-r.notifyCh:
+			r.inFlight--
+			log.Printf("Task %s marked as completed", result.taskID)
+			if result.err != nil && nodeErrorPolicy(r.nodeMap[result.taskID]).Action == OnErrorAbort {
+				r.fail(result)
+				return
+			}
+			r.complete(result)
+			r.dispatch()
+
+			// Nothing running and nothing left to start: the run is over
+			if r.inFlight == 0 && r.ready.len() == 0 {
+				if stalled := r.unsettled(); len(stalled) > 0 {
+					err := fmt.Errorf("%d node(s) never became ready: %v", len(stalled), stalled)
+					log.Printf("Execution %s stalled: %v", r.ID, err)
+					if r.finish(err) {
+						r.emit("execution-error", map[string]interface{}{
+							"error":   err.Error(),
+							"nodeIds": stalled,
+						})
+					}
+					return
+				}
+				log.Printf("All tasks completed. Execution %s finished.", r.ID)
+				if r.finish(nil) {
+					r.emit("execution-completed", nil)
+				}
+				return
+			}
+		case <-r.ctx.Done():
+			log.Printf("Execution %s stopped due to cancellation", r.ID)
+			return
+		case <-r.timedOut:
+			log.Printf("Execution %s timed out after %v", r.ID, r.runTimeout())
+			if r.finish(nil) {
+				r.emit("execution-timed-out", map[string]interface{}{
+					"timeout": r.runTimeout().Milliseconds(),
+				})
+			}
+			return
+		}
+	}
+}
+
+// fail ends the run because a node failed with the abort policy.
+func (r *ExecutionRun) fail(result taskResult) {
+	node := r.nodeMap[result.taskID]
+	log.Printf("Execution %s failed: node %s failed after %d attempt(s): %v", r.ID, node.ID, result.attempts, result.err)
+	if !r.finish(fmt.Errorf("node %s failed: %w", node.ID, result.err)) {
+		return
+	}
+	r.emit("execution-failed", map[string]interface{}{
+		"taskID":   node.ID,
+		"type":     node.Type,
+		"error":    result.err.Error(),
+		"attempts": result.attempts,
+	})
+}
+
+// finish shuts the run down after it ended on its own, with outcome nil if
+// it succeeded. A sub-run hands outcome to its CallFlowNode. The top run
+// reports true, telling the caller to emit how the run ended, unless it was
+// stopped or aborted in the meantime.
+func (r *ExecutionRun) finish(outcome error) bool {
+	if r.parent != nil {
+		r.cancel()
+		r.outcome = outcome
+		close(r.ended)
+		return false
+	}
+	ended := r.app.endRun(r)
+	r.stop()
+	return ended
+}
+
+// dispatch hands ready tasks to the task queue. Sequential policies only
+// release the next task once nothing else is in flight.
+func (r *ExecutionRun) dispatch() {
+	for r.ready.len() > 0 {
+		if r.options.sequential() && r.inFlight > 0 {
+			return
+		}
+		task, _ := r.ready.pop()
+		r.inFlight++
+		r.queue.Enqueue(task)
+	}
+}
+
+//=============================================== Scheduling ===============================================
+
+// complete records a finished task, fires or skips its outgoing edges and
+// queues every node that became ready as a result. A node whose failure is
+// routed fires only its error handle, which otherwise never fires.
+func (r *ExecutionRun) complete(result taskResult) {
+	node := r.nodeMap[result.taskID]
+	var ready []Task
+
+	if body, isLoop := r.loops[node.ID]; isLoop && slices.Contains(result.handles, HandleLoopBody) {
+		// Another iteration: the body starts over while the exit edges wait
+		r.status[node.ID] = nodeLooping
+		r.resetLoopBody(body)
+		for _, i := range r.outgoing[node.ID] {
+			if r.edges[i].SourceHandle == HandleLoopBody {
+				r.edgeStatus[i] = edgeFired
+				ready = r.settle(r.edges[i].Target, ready)
+			}
+		}
+	} else {
+		r.status[node.ID] = nodeDone
+		declared := handlesOf(node)
+		routed := result.err != nil && nodeErrorPolicy(node).Action == OnErrorRoute
+		for _, i := range r.outgoing[node.ID] {
+			edge := r.edges[i]
+			var fire bool
+			switch {
+			case edge.SourceHandle == HandleError:
+				fire = routed
+			case routed:
+				fire = false
+			default:
+				fire = !slices.Contains(declared, edge.SourceHandle) || slices.Contains(result.handles, edge.SourceHandle)
+			}
+			if fire {
+				r.edgeStatus[i] = edgeFired
+			} else {
+				r.edgeStatus[i] = edgeSkipped
+			}
+			ready = r.settle(edge.Target, ready)
+		}
+	}
+
+	ready = append(ready, r.iterateLoops()...)
+	r.ready.push(ready...)
+}
+
+// settle decides the fate of a pending node once none of its incoming edges
+// are pending: it runs if any of them fired and is skipped otherwise. Skips
+// propagate downstream. Edges back from a loop body are ignored, as the
+// scheduler decides when a loop runs again. Ready tasks are appended to ready.
+func (r *ExecutionRun) settle(nodeID string, ready []Task) []Task {
+	if r.status[nodeID] != nodePending {
+		return ready
+	}
+	fired := false
+	for _, i := range r.incoming[nodeID] {
+		if r.backEdge[i] {
+			continue
+		}
+		switch r.edgeStatus[i] {
+		case edgePending:
+			return ready
+		case edgeFired:
+			fired = true
+		}
+	}
+
+	if fired {
+		r.status[nodeID] = nodeScheduled
+		return append(ready, taskForNode(r.nodeMap[nodeID]))
+	}
+
+	log.Printf("Task %s skipped: none of its incoming edges ran", nodeID)
+	r.status[nodeID] = nodeSkipped
+	r.recordResult(nodeID, "skipped", nil)
+	r.emit("task-skipped", map[string]interface{}{"taskID": nodeID})
+	for _, i := range r.outgoing[nodeID] {
+		r.edgeStatus[i] = edgeSkipped
+		ready = r.settle(r.edges[i].Target, ready)
+	}
+	return ready
+}
+
+// resetLoopBody returns a loop body to its initial state so it can run again.
+func (r *ExecutionRun) resetLoopBody(body map[string]bool) {
+	r.stateMu.Lock()
+	for id := range body {
+		r.status[id] = nodePending
+		delete(r.nodeState, id)
+	}
+	r.stateMu.Unlock()
+	for i, edge := range r.edges {
+		if body[edge.Source] {
+			r.edgeStatus[i] = edgePending
+		}
+	}
+}
+
+// iterateLoops re-queues every LoopNode whose body has settled, so it can
+// start its next iteration or exit.
+func (r *ExecutionRun) iterateLoops() []Task {
+	var ready []Task
+	for _, id := range r.loopIDs {
+		if r.status[id] != nodeLooping {
+			continue
+		}
+		settled := true
+		for bodyID := range r.loops[id] {
+			if !r.status[bodyID].settled() {
+				settled = false
+				break
+			}
+		}
+		if settled {
+			r.status[id] = nodeScheduled
+			ready = append(ready, taskForNode(r.nodeMap[id]))
+		}
+	}
+	return ready
+}
+
+// unsettled lists the nodes that neither ran nor were skipped.
+func (r *ExecutionRun) unsettled() []string {
+	var ids []string
+	for id := range r.nodeMap {
+		if !r.status[id].settled() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//=============================================== Task Queue ===============================================
+
+// NewTaskQueue initializes a new TaskQueue for run. The queue shuts down
+// when the run's context is cancelled.
+func NewTaskQueue(run *ExecutionRun, bufferSize int) *TaskQueue {
+	ctx, cancel := context.WithCancel(run.ctx)
+	return &TaskQueue{
+		tasks:  make(chan Task, bufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+		run:    run,
+	}
+}
+
+// Start initializes worker goroutines to process tasks.
+func (q *TaskQueue) Start(workerCount int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+	for i := 0; i < workerCount; i++ {
+		q.wg.Add(1)
+		go q.worker(i)
+	}
+	log.Printf("TaskQueue started with %d workers", workerCount)
+}
+
+// worker processes tasks from the queue.
+func (q *TaskQueue) worker(workerID int) {
+	defer q.wg.Done()
+	log.Printf("Worker %d started", workerID)
+	for {
+		select {
+		case task := <-q.tasks:
+			// A cancelled queue may still have buffered tasks; drop them
+			if q.ctx.Err() != nil {
+				log.Printf("Worker %d stopping: context canceled", workerID)
+				return
+			}
+			// Hold the task back while the run is paused
+			if err := q.run.gate.Wait(q.ctx); err != nil {
+				log.Printf("Worker %d stopping: context canceled", workerID)
+				return
+			}
+			log.Printf("Worker %d processing task %s of type %s", workerID, task.ID, task.Type)
+			q.run.runTask(task)
+		case <-q.ctx.Done():
+			log.Printf("Worker %d stopping: context canceled", workerID)
+			return
+		}
+	}
+}
+
+// Enqueue adds a task to the queue.
+func (q *TaskQueue) Enqueue(task Task) {
+	select {
+	case q.tasks <- task:
+		log.Printf("Enqueued task %s of type %s", task.ID, task.Type)
+	case <-q.ctx.Done():
+		log.Println("Task queue is closed. Cannot enqueue task:", task.ID)
+	}
+}
+
+// Stop shuts down the task queue and waits for its workers. The tasks
+// channel is never closed, so a late Enqueue from the scheduler is dropped
+// rather than panicking; each run gets a new queue anyway.
+func (q *TaskQueue) Stop() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if !q.started {
+		return
+	}
+	q.cancel()
+	q.wg.Wait()
+	q.started = false
+	log.Println("TaskQueue has been stopped")
+}
@@ -0,0 +1,73 @@
+// run_test.go
+
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// terminalEvents are the events that say how a run ended.
+var terminalEvents = []string{
+	"execution-completed", "execution-stopped", "execution-error",
+	"execution-failed", "execution-timed-out", "execution-aborted",
+}
+
+func TestStopDuringCompletionEmitsOneTerminalEvent(t *testing.T) {
+	flow := chain(node("a", "KeyTap", `{"key":"a"}`))
+	for i := 0; i < 50; i++ {
+		app := NewAppWithDriver(NewRecordingDriver())
+		events := recordEvents(app)
+		if _, err := app.StartExecution(flow); err != nil {
+			t.Fatal(err)
+		}
+		// Land the stop anywhere around the end of the run
+		time.Sleep(time.Duration(rand.Intn(150)) * time.Millisecond)
+		app.StopExecution()
+		waitIdle(t, app)
+
+		ended := func() []string {
+			var out []string
+			for _, name := range terminalEvents {
+				for range events.named(name) {
+					out = append(out, name)
+				}
+			}
+			return out
+		}
+		// The terminal event follows the end of the run, so give it a moment
+		deadline := time.Now().Add(time.Second)
+		for len(ended()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if ended := ended(); len(ended) != 1 {
+			t.Fatalf("run %d emitted %v, want exactly one terminal event", i, ended)
+		}
+	}
+}
+
+func TestUnreachableNodesAreExcluded(t *testing.T) {
+	flow := graph([]testNode{
+		node("a", "TypeString", `{"text":"a"}`),
+		node("b", "TypeString", `{"text":"b"}`),
+		node("c", "TypeString", `{"text":"c"}`),
+	}, "start->a", "b->c")
+
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, flow)
+
+	assertActions(t, driver.ActionStrings(), "type(a)")
+	warnings := events.named("execution-warning")
+	if len(warnings) != 1 || !slices.Equal(warnings[0].payload["nodeIds"].([]string), []string{"b", "c"}) {
+		t.Errorf("execution-warning events: %+v", warnings)
+	}
+	// The run ends once the reachable nodes are done, without waiting on b and c
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
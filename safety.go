@@ -0,0 +1,210 @@
+// safety.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// SafetySettings configure the fail-safe monitor, which aborts a run when
+// the user takes back the mouse. It is off until enabled in the settings.
+type SafetySettings struct {
+	Enabled bool `json:"enabled"`
+	// Corners aborts when the cursor reaches a corner of the primary screen
+	// the flow didn't move it to.
+	Corners bool `json:"corners"`
+	// Interference aborts when the cursor is moved away from where the flow
+	// last put it.
+	Interference bool `json:"interference"`
+	// Tolerance is how far, in pixels, the cursor may drift before it
+	// counts as moved, and how close to a corner counts as in it.
+	Tolerance    int     `json:"tolerance"`
+	PollInterval float64 `json:"pollInterval"` // ms
+}
+
+func defaultSafetySettings() SafetySettings {
+	return SafetySettings{
+		Enabled:      false,
+		Corners:      true,
+		Interference: true,
+		Tolerance:    25,
+		PollInterval: 50,
+	}
+}
+
+func (s SafetySettings) validate() error {
+	if s.Tolerance < 0 {
+		return fmt.Errorf("safety.tolerance: must be >= 0, got %d", s.Tolerance)
+	}
+	if s.PollInterval < 1 {
+		return fmt.Errorf("safety.pollInterval: must be >= 1, got %v", s.PollInterval)
+	}
+	return nil
+}
+
+//=============================================== Screen Size ===============================================
+
+// screenSizer reports the size of the primary screen. Drivers implement it
+// so the monitor can find the corners.
+type screenSizer interface {
+	ScreenSize() (width, height int)
+}
+
+func (robotgoDriver) ScreenSize() (int, int) {
+	return robotgo.GetScreenSize()
+}
+
+func (d *RecordingDriver) ScreenSize() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.screen != nil {
+		size := d.screen.Bounds().Size()
+		return size.X, size.Y
+	}
+	return d.ScreenWidth, d.ScreenHeight
+}
+
+//=============================================== Pointer Tracking ===============================================
+
+// pointerTracker wraps an InputDriver and remembers where the flow last put
+// the cursor, and whether it is in the middle of a smooth move. It holds its
+// lock across instant moves so a sample never sees the cursor moved but the
+// position not yet updated.
+type pointerTracker struct {
+	InputDriver
+	mu     sync.Mutex
+	x, y   int
+	moving bool
+}
+
+func newPointerTracker(driver InputDriver) *pointerTracker {
+	t := &pointerTracker{InputDriver: driver}
+	t.x, t.y = driver.Location()
+	return t
+}
+
+func (t *pointerTracker) Move(x, y int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.InputDriver.Move(x, y)
+	t.x, t.y = x, y
+}
+
+func (t *pointerTracker) MoveSmooth(x, y int, low, high float64, delay int) bool {
+	t.mu.Lock()
+	t.moving = true
+	t.mu.Unlock()
+
+	ok := t.InputDriver.MoveSmooth(x, y, low, high, delay)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.moving = false
+	t.x, t.y = x, y
+	return ok
+}
+
+func (t *pointerTracker) MoveWithSpeed(m MouseMotion) error {
+	if !m.Smooth {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		err := t.InputDriver.MoveWithSpeed(m)
+		t.x, t.y = m.X, m.Y
+		return err
+	}
+	t.mu.Lock()
+	t.moving = true
+	t.mu.Unlock()
+
+	err := t.InputDriver.MoveWithSpeed(m)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.moving = false
+	t.x, t.y = m.X, m.Y
+	return err
+}
+
+// pointerSample is the real cursor position next to where the flow put it.
+type pointerSample struct {
+	x, y         int
+	wantX, wantY int
+	moving       bool // a smooth move is in progress
+}
+
+// sample reads the real cursor position.
+func (t *pointerTracker) sample() pointerSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	x, y := t.InputDriver.Location()
+	return pointerSample{x: x, y: y, wantX: t.x, wantY: t.y, moving: t.moving}
+}
+
+//=============================================== Monitor ===============================================
+
+// monitorSafety polls the real cursor until the run ends and aborts it when
+// the user interferes. During a smooth move only the corners are checked, as
+// the cursor is between where it was and where it is going.
+func (r *ExecutionRun) monitorSafety(settings SafetySettings, tracker *pointerTracker) {
+	width, height := 0, 0
+	if sizer, ok := tracker.InputDriver.(screenSizer); ok {
+		width, height = sizer.ScreenSize()
+	}
+	ticker := time.NewTicker(time.Duration(settings.PollInterval * float64(time.Millisecond)))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s := tracker.sample()
+		moved := distance(s.x, s.y, s.wantX, s.wantY) > float64(settings.Tolerance)
+
+		var reason string
+		switch {
+		case settings.Corners && moved && inCorner(s.x, s.y, width, height, settings.Tolerance):
+			reason = "cursor moved into a screen corner"
+		case settings.Interference && moved && !s.moving:
+			reason = fmt.Sprintf("cursor moved by the user (expected %d, %d)", s.wantX, s.wantY)
+		default:
+			continue
+		}
+		r.app.abortRun(r, reason, s.x, s.y)
+		return
+	}
+}
+
+// inCorner reports whether x, y is within margin of a corner of a
+// width×height screen. Only the top-left corner is known without a size.
+func inCorner(x, y, width, height, margin int) bool {
+	nearLeft, nearTop := x <= margin, y <= margin
+	if width <= 0 || height <= 0 {
+		return nearLeft && nearTop
+	}
+	nearRight, nearBottom := x >= width-1-margin, y >= height-1-margin
+	return (nearLeft || nearRight) && (nearTop || nearBottom)
+}
+
+// abortRun stops run because the safety monitor tripped, which releases every
+// key and button the flow is holding.
+func (a *App) abortRun(run *ExecutionRun, reason string, x, y int) {
+	if !a.endRun(run) {
+		return
+	}
+	released := run.stop()
+	log.Printf("Execution %s aborted: %s at %d, %d (released %v)", run.ID, reason, x, y, released)
+	run.emit("execution-aborted", map[string]interface{}{
+		"reason":   reason,
+		"x":        x,
+		"y":        y,
+		"released": released,
+	})
+}
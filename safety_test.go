@@ -0,0 +1,73 @@
+// safety_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// runDisturbed starts a delay on an App with safety, moves the cursor from
+// the middle of the screen to x, y as the user would and returns the events
+// of the run.
+func runDisturbed(t *testing.T, safety SafetySettings, x, y int) *eventLog {
+	t.Helper()
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	app.settings.Safety = safety
+	events := recordEvents(app)
+	driver.SetLocation(400, 300)
+
+	if _, err := app.StartExecution(chain(node("d", "DelayNode", `{"time":300}`))); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	driver.SetLocation(x, y)
+	waitIdle(t, app)
+	// The terminal event follows the end of the run
+	time.Sleep(20 * time.Millisecond)
+	return events
+}
+
+func TestSafetyIsOptIn(t *testing.T) {
+	if defaultSettings().Safety.Enabled {
+		t.Fatal("the fail-safe is enabled by default")
+	}
+	events := runDisturbed(t, defaultSafetySettings(), 500, 500)
+	if got := events.named("execution-aborted"); len(got) != 0 {
+		t.Errorf("aborted without the fail-safe enabled: %+v", got)
+	}
+	if len(events.named("execution-completed")) != 1 {
+		t.Error("run did not complete")
+	}
+}
+
+func TestSafetyAborts(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   int
+		reason string
+	}{
+		{"interference", 500, 500, "cursor moved by the user"},
+		{"corner", 0, 0, "cursor moved into a screen corner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			safety := defaultSafetySettings()
+			safety.Enabled = true
+			events := runDisturbed(t, safety, tt.x, tt.y)
+
+			aborted := events.named("execution-aborted")
+			if len(aborted) != 1 {
+				t.Fatalf("got %d execution-aborted events", len(aborted))
+			}
+			if reason, _ := aborted[0].payload["reason"].(string); !strings.Contains(reason, tt.reason) {
+				t.Errorf("reason %q, want it to contain %q", reason, tt.reason)
+			}
+			if got := events.named("execution-completed"); len(got) != 0 {
+				t.Error("an aborted run also completed")
+			}
+		})
+	}
+}
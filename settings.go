@@ -0,0 +1,93 @@
+// settings.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"Keypress/utils"
+)
+
+// Settings are the application preferences, saved in the config directory.
+type Settings struct {
+	Hotkeys  HotkeySettings  `json:"hotkeys"`
+	Safety   SafetySettings  `json:"safety"`
+	Timeouts TimeoutSettings `json:"timeouts"`
+}
+
+// HotkeySettings are the global hotkeys that control runs. Each is a chord
+// such as "ctrl+alt+s", or a sequence of chords separated by spaces such as
+// "esc esc", pressed within half a second of each other. Empty disables it.
+type HotkeySettings struct {
+	Start string `json:"start"` // rerun the last flow, or run the last saved one
+	Stop  string `json:"stop"`
+	Pause string `json:"pause"` // pause, or resume if paused
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		Hotkeys: HotkeySettings{
+			Start: "ctrl+alt+s",
+			Stop:  "esc esc",
+			Pause: "pause",
+		},
+		Safety: defaultSafetySettings(),
+	}
+}
+
+// validate checks that every hotkey parses and every limit is in range.
+func (s Settings) validate() error {
+	for action, hotkey := range s.Hotkeys.bindings() {
+		if _, err := parseHotkey(hotkey); err != nil {
+			return fmt.Errorf("hotkeys.%s: %w", action, err)
+		}
+	}
+	if err := s.Safety.validate(); err != nil {
+		return err
+	}
+	return s.Timeouts.validate()
+}
+
+// bindings maps each hotkey action to its hotkey, leaving out disabled ones.
+func (h HotkeySettings) bindings() map[string]string {
+	out := make(map[string]string)
+	for action, hotkey := range map[string]string{
+		hotkeyStart: h.Start,
+		hotkeyStop:  h.Stop,
+		hotkeyPause: h.Pause,
+	} {
+		if hotkey != "" {
+			out[action] = hotkey
+		}
+	}
+	return out
+}
+
+// sequences returns the chords of each hotkey by action. Hotkeys that don't
+// parse are skipped; they are rejected when settings are saved.
+func (h HotkeySettings) sequences() map[string][]keyChord {
+	out := make(map[string][]keyChord)
+	for action, hotkey := range h.bindings() {
+		if chords, err := parseHotkey(hotkey); err == nil {
+			out[action] = chords
+		}
+	}
+	return out
+}
+
+// loadSettings returns the saved settings, with defaults for anything not
+// saved. Unreadable or invalid settings are logged and replaced by the
+// defaults.
+func loadSettings() Settings {
+	settings := defaultSettings()
+	if _, err := utils.LoadSettings(&settings); err != nil {
+		log.Printf("Using default settings: %v", err)
+		return defaultSettings()
+	}
+	if err := settings.validate(); err != nil {
+		log.Printf("Using default settings: %v", err)
+		return defaultSettings()
+	}
+	return settings
+}
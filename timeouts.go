@@ -0,0 +1,89 @@
+// timeouts.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutSettings are the default time limits, in milliseconds. 0 means no
+// limit. Time spent paused or stopped at a breakpoint doesn't count.
+type TimeoutSettings struct {
+	Run  float64 `json:"run"`  // whole run, unless the flow sets options.timeout
+	Node float64 `json:"node"` // each node, unless it sets nodeTimeout
+}
+
+func (t TimeoutSettings) validate() error {
+	if t.Run < 0 {
+		return fmt.Errorf("timeouts.run: must be >= 0, got %v", t.Run)
+	}
+	if t.Node < 0 {
+		return fmt.Errorf("timeouts.node: must be >= 0, got %v", t.Node)
+	}
+	return nil
+}
+
+// errTaskTimeout is wrapped by the error of a node that ran out of time.
+var errTaskTimeout = errors.New("timed out")
+
+// readNodeTimeout reads a node's own time limit from "nodeTimeout" in ms. ok
+// is false if the node doesn't set one.
+func readNodeTimeout(data map[string]interface{}) (timeout time.Duration, ok bool, err error) {
+	r := newDataReader(data)
+	if !r.Has("nodeTimeout") {
+		return 0, false, nil
+	}
+	ms := r.Float("nodeTimeout", 0)
+	r.Min("nodeTimeout", ms, 0)
+	return time.Duration(ms * float64(time.Millisecond)), true, r.Err()
+}
+
+// nodeTimeout returns the time limit of a validated node, or 0 for none.
+func (r *ExecutionRun) nodeTimeout(node Node) time.Duration {
+	if timeout, ok, _ := readNodeTimeout(node.Data); ok {
+		return timeout
+	}
+	return time.Duration(r.timeouts.Node * float64(time.Millisecond))
+}
+
+// runTimeout returns the time limit of the whole run, or 0 for none.
+func (r *ExecutionRun) runTimeout() time.Duration {
+	ms := r.timeouts.Run
+	if r.options.Timeout > 0 {
+		ms = r.options.Timeout
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// withTimeout returns a context that is cancelled with errTaskTimeout once
+// timeout of unpaused time has passed. A zero timeout never expires.
+func (r *ExecutionRun) withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	if timeout > 0 {
+		go func() {
+			if r.gate.Sleep(ctx, timeout) == nil {
+				cancel(errTaskTimeout)
+			}
+		}()
+	}
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// timeRun closes r.timedOut once the run has used up its time limit. Time
+// spent paused or held by the debugger doesn't count, so the clock is
+// checked once a second.
+func (r *ExecutionRun) timeRun(timeout time.Duration) {
+	for remaining := timeout; remaining > 0; {
+		tick := min(remaining, time.Second)
+		if r.gate.Sleep(r.ctx, tick) != nil {
+			return
+		}
+		if !r.debug.isHolding() {
+			remaining -= tick
+		}
+	}
+	close(r.timedOut)
+}
@@ -0,0 +1,108 @@
+// timeouts_test.go
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		settings float64
+		timedOut bool
+	}{
+		{"nodeTimeout", `{"time":5000,"nodeTimeout":30}`, 0, true},
+		{"timeouts.node", `{"time":5000}`, 30, true},
+		{"nodeTimeout overrides timeouts.node", `{"time":60,"nodeTimeout":0}`, 30, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := NewRecordingDriver()
+			app := NewAppWithDriver(driver)
+			app.settings.Timeouts.Node = tt.settings
+			events := recordEvents(app)
+			began := time.Now()
+			// KeyTap waits 100 ms after the key, longer than timeouts.node
+			runFlowOn(t, app, chain(node("wait", "DelayNode", tt.data), node("next", "KeyTap", `{"key":"n","nodeTimeout":0}`)))
+
+			if !tt.timedOut {
+				assertActions(t, driver.ActionStrings(), "keyTap(n)")
+				if n := len(events.named("task-timed-out")); n != 0 {
+					t.Errorf("%d task-timed-out events, want none", n)
+				}
+				return
+			}
+			if elapsed := time.Since(began); elapsed > time.Second {
+				t.Errorf("the node ran for %v", elapsed)
+			}
+			assertActions(t, driver.ActionStrings())
+			timedOut := events.named("task-timed-out")
+			if len(timedOut) != 1 || timedOut[0].payload["taskID"] != "wait" ||
+				timedOut[0].payload["type"] != "DelayNode" || timedOut[0].payload["timeout"] != int64(30) {
+				t.Errorf("task-timed-out events: %+v", timedOut)
+			}
+			if failed := events.wait("execution-failed"); len(failed) != 1 || failed[0].payload["taskID"] != "wait" {
+				t.Errorf("execution-failed events: %+v", failed)
+			}
+		})
+	}
+}
+
+func TestNodeTimeoutRetries(t *testing.T) {
+	driver := NewRecordingDriver()
+	app := NewAppWithDriver(driver)
+	events := recordEvents(app)
+	runFlowOn(t, app, graph([]testNode{
+		node("wait", "DelayNode", `{"time":5000,"nodeTimeout":20,"onError":{"action":"error","retries":1,"retryDelay":0}}`),
+		node("handler", "KeyTap", `{"key":"h"}`),
+		node("next", "KeyTap", `{"key":"n"}`),
+	}, "start->wait", "wait:error->handler", "wait->next"))
+
+	assertActions(t, driver.ActionStrings(), "keyTap(h)")
+	if n := len(events.named("task-timed-out")); n != 2 {
+		t.Errorf("%d task-timed-out events, want one per attempt", n)
+	}
+	if n := len(events.wait("execution-completed")); n != 1 {
+		t.Errorf("%d execution-completed events, want 1", n)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	flow := chain(node("wait", "DelayNode", `{"time":5000}`), node("next", "KeyTap", `{"key":"n"}`))
+	tests := []struct {
+		name     string
+		flow     string
+		settings float64
+	}{
+		{"options.timeout", withOptions(flow, `{"timeout":50}`), 0},
+		{"timeouts.run", flow, 50},
+		{"options.timeout overrides timeouts.run", withOptions(flow, `{"timeout":50}`), 60000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := NewRecordingDriver()
+			app := NewAppWithDriver(driver)
+			app.settings.Timeouts.Run = tt.settings
+			events := recordEvents(app)
+			began := time.Now()
+			runFlowOn(t, app, tt.flow)
+
+			if elapsed := time.Since(began); elapsed > time.Second {
+				t.Errorf("the run went on for %v", elapsed)
+			}
+			assertActions(t, driver.ActionStrings())
+			timedOut := events.wait("execution-timed-out")
+			if len(timedOut) != 1 || timedOut[0].payload["timeout"] != int64(50) {
+				t.Errorf("execution-timed-out events: %+v", timedOut)
+			}
+			for _, name := range []string{"execution-completed", "execution-failed", "task-timed-out"} {
+				if n := len(events.named(name)); n != 0 {
+					t.Errorf("%d %s events after the run timed out", n, name)
+				}
+			}
+		})
+	}
+}
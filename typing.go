@@ -0,0 +1,191 @@
+// typing.go
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// HumanTyping types text a key at a time with human-like timing. It is read
+// from the "humanize" object of a TypeString's data.
+type HumanTyping struct {
+	WPM          float64 `json:"wpm"`          // words of 5 characters per minute
+	Jitter       float64 `json:"jitter"`       // spread of each delay, as a fraction of the mean
+	Distribution string  `json:"distribution"` // "normal" or "uniform"
+	// PunctuationPause is the extra ms after , ; and :, doubled after . ! ?
+	// and newlines.
+	PunctuationPause float64 `json:"punctuationPause"`
+	TypoRate         float64 `json:"typoRate"`    // chance per letter of hitting a neighbouring key first
+	BurstLength      int     `json:"burstLength"` // mean characters per burst; 0 for no bursts
+	BurstSpeed       float64 `json:"burstSpeed"`  // how many times faster keys come within a burst
+	BurstPause       float64 `json:"burstPause"`  // ms between bursts
+	Seed             int64   `json:"seed"`        // 0 for a different timing each run
+}
+
+func readHumanTyping(r *dataReader) *HumanTyping {
+	if !r.Has("humanize") {
+		return nil
+	}
+	o := r.Object("humanize")
+	h := &HumanTyping{
+		WPM:              o.Float("wpm", 60),
+		Jitter:           o.Float("jitter", 0.3),
+		Distribution:     o.OneOf("distribution", "normal", "normal", "uniform"),
+		PunctuationPause: o.Float("punctuationPause", 200),
+		TypoRate:         o.Float("typoRate", 0),
+		BurstLength:      o.Int("burstLength", 0),
+		BurstSpeed:       o.Float("burstSpeed", 1.5),
+		BurstPause:       o.Float("burstPause", 250),
+		Seed:             readSeed(o),
+	}
+	o.Min("wpm", h.WPM, 1)
+	o.Min("jitter", h.Jitter, 0)
+	if h.Jitter > 1 {
+		o.Fail("jitter", "number between 0 and 1", h.Jitter, "")
+	}
+	o.Min("punctuationPause", h.PunctuationPause, 0)
+	o.Min("typoRate", h.TypoRate, 0)
+	if h.TypoRate > 1 {
+		o.Fail("typoRate", "number between 0 and 1", h.TypoRate, "")
+	}
+	o.Min("burstLength", float64(h.BurstLength), 0)
+	o.Min("burstSpeed", h.BurstSpeed, 1)
+	o.Min("burstPause", h.BurstPause, 0)
+	return h
+}
+
+// maxSeed bounds the seed: flow JSON numbers are float64, so larger integers
+// would already have been rounded and wouldn't reproduce the timing asked for.
+const maxSeed = 1 << 53
+
+func readSeed(o *dataReader) int64 {
+	f := o.Float("seed", 0)
+	if f != math.Trunc(f) || math.Abs(f) > maxSeed {
+		o.Fail("seed", "integer between -2^53 and 2^53", f, "")
+		return 0
+	}
+	return int64(f)
+}
+
+// rng returns the random source for one run of the node.
+func (h *HumanTyping) rng() *rand.Rand {
+	if h.Seed != 0 {
+		return rand.New(rand.NewSource(h.Seed))
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// keystroke is one step of humanized typing: wait Delay, then type Text,
+// or tap backspace if Text is empty.
+type keystroke struct {
+	Delay time.Duration
+	Text  string
+}
+
+// planTyping decides every keystroke for typing text and how long to wait
+// before each. The same rng state always gives the same plan.
+func planTyping(text string, h *HumanTyping, rng *rand.Rand) []keystroke {
+	mean := 60000 / (h.WPM * 5) // ms per character
+	var plan []keystroke
+	burst := 0 // characters left in the current burst
+	pause := 0.0
+
+	delay := func() time.Duration {
+		var ms float64
+		switch h.Distribution {
+		case "uniform":
+			ms = mean * (1 + h.Jitter*(rng.Float64()*2-1))
+		default:
+			ms = mean * (1 + h.Jitter*rng.NormFloat64())
+		}
+		// Nobody types faster than a fifth of their mean
+		ms = max(ms, mean/5)
+		if h.BurstLength > 0 {
+			ms /= h.BurstSpeed
+		}
+		ms += pause
+		pause = 0
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	for _, c := range text {
+		if h.BurstLength > 0 {
+			if burst == 0 {
+				if len(plan) > 0 {
+					pause += h.BurstPause
+				}
+				burst = 1 + rng.Intn(2*h.BurstLength-1)
+			}
+			burst--
+		}
+
+		if h.TypoRate > 0 && rng.Float64() < h.TypoRate {
+			if typo, ok := typoFor(c, rng); ok {
+				plan = append(plan, keystroke{Delay: delay(), Text: string(typo)})
+				// Noticing the mistake takes a moment
+				pause += mean * (1 + rng.Float64()*2)
+				plan = append(plan, keystroke{Delay: delay()})
+			}
+		}
+		plan = append(plan, keystroke{Delay: delay(), Text: string(c)})
+
+		switch {
+		case strings.ContainsRune(".!?\n", c):
+			pause += 2 * h.PunctuationPause
+		case strings.ContainsRune(",;:", c):
+			pause += h.PunctuationPause
+		}
+	}
+	return plan
+}
+
+// qwertyRows are the rows of a QWERTY keyboard, for picking a neighbouring
+// key to mistype.
+var qwertyRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// typoFor returns a key next to c on the same or an adjacent row, keeping
+// its case. ok is false if c isn't on the keyboard.
+func typoFor(c rune, rng *rand.Rand) (typo rune, ok bool) {
+	lower := unicode.ToLower(c)
+	for row, keys := range qwertyRows {
+		col := strings.IndexRune(keys, lower)
+		if col < 0 {
+			continue
+		}
+		var near []rune
+		for r := max(row-1, 0); r <= min(row+1, len(qwertyRows)-1); r++ {
+			for i, k := range qwertyRows[r] {
+				if i >= col-1 && i <= col+1 && k != lower {
+					near = append(near, k)
+				}
+			}
+		}
+		typo = near[rng.Intn(len(near))]
+		if unicode.IsUpper(c) {
+			typo = unicode.ToUpper(typo)
+		}
+		return typo, true
+	}
+	return 0, false
+}
+
+// typeHuman types text as planned, pausing between keystrokes.
+func typeHuman(ec *ExecContext, text string, h *HumanTyping, rng *rand.Rand) error {
+	for _, k := range planTyping(text, h, rng) {
+		if err := ec.Sleep(k.Delay); err != nil {
+			return err
+		}
+		if k.Text == "" {
+			if err := ec.Driver.KeyTap("backspace"); err != nil {
+				return err
+			}
+			continue
+		}
+		ec.Driver.TypeStr(k.Text)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// typing_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// humanize decodes the humanize object of a TypeString's data from JSON.
+func humanize(t *testing.T, obj string) (*HumanTyping, error) {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"humanize":`+obj+`}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	r := newDataReader(data)
+	h := readHumanTyping(r)
+	return h, r.Err()
+}
+
+func delays(plan []keystroke) []time.Duration {
+	out := make([]time.Duration, len(plan))
+	for i, k := range plan {
+		out[i] = k.Delay
+	}
+	return out
+}
+
+func TestHumanTypingSeed(t *testing.T) {
+	const text = "The quick brown fox, jumps over the lazy dog."
+	humanized := `{"wpm":3000,"typoRate":0.2,"burstLength":4,"seed":42}`
+	flow := chain(node("type", "TypeString", `{"text":"`+text+`","humanize":`+humanized+`}`))
+
+	first, second := runFlow(t, flow), runFlow(t, flow)
+	if !slices.Contains(first, "keyTap(backspace)") {
+		t.Fatalf("no typos were made: %q", first)
+	}
+	assertActions(t, second, first...)
+
+	h, err := humanize(t, humanized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := planTyping(text, h, h.rng()), planTyping(text, h, h.rng())
+	if !slices.Equal(a, b) {
+		t.Errorf("plans differ:\n %v\n %v", a, b)
+	}
+	h.Seed = 43
+	if slices.Equal(delays(a), delays(planTyping(text, h, h.rng()))) {
+		t.Error("another seed gave the same timing")
+	}
+}
+
+func TestHumanTypingSeedRange(t *testing.T) {
+	for _, seed := range []string{"9007199254740992", "-9007199254740992"} {
+		h, err := humanize(t, `{"seed":`+seed+`}`)
+		if err != nil || h.Seed != 1<<53 && h.Seed != -1<<53 {
+			t.Errorf("seed %s: got %d, %v", seed, h.Seed, err)
+		}
+	}
+	for _, seed := range []string{"9007199254740994", "1.5"} {
+		if _, err := humanize(t, `{"seed":`+seed+`}`); err == nil {
+			t.Errorf("seed %s was accepted", seed)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 const (
 	AppName           = "Keypress"
 	LastOpenedFileKey = "last_opened_file.txt"
+	SettingsFileKey   = "settings.json"
 )
 
 // GetAppConfigDir returns the application-specific config directory
@@ -44,6 +45,19 @@ func GetAppDataDir() (string, error) {
 	return dir, nil
 }
 
+// GetTemplatesDir returns the directory holding template images for image matching
+func GetTemplatesDir() (string, error) {
+	dataDir, err := GetAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, "templates")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	return dir, nil
+}
+
 // SaveLastOpenedFile saves the path of the last opened file
 func SaveLastOpenedFile(filePath string) error {
 	configDir, err := GetAppConfigDir()
@@ -74,6 +88,48 @@ func GetLastOpenedFile() (string, error) {
 	return string(data), nil
 }
 
+// SaveSettings writes the application settings to the config directory
+func SaveSettings(settings interface{}) error {
+	configDir, err := GetAppConfigDir()
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	settingsPath := filepath.Join(configDir, SettingsFileKey)
+	if err := os.WriteFile(settingsPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return nil
+}
+
+// LoadSettings reads the application settings into settings. It reports
+// false, leaving settings untouched, if none have been saved yet.
+func LoadSettings(settings interface{}) (bool, error) {
+	configDir, err := GetAppConfigDir()
+	if err != nil {
+		return false, err
+	}
+
+	settingsPath := filepath.Join(configDir, SettingsFileKey)
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if err := json.Unmarshal(data, settings); err != nil {
+		return false, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	return true, nil
+}
+
 // SaveFlowData saves the flow data to the specified location
 func SaveFlowData(data interface{}, filename string) (string, error) {
 	// Get the app data directory
@@ -0,0 +1,368 @@
+// validate.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Issue severities. Errors block execution, warnings are informational.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// FlowIssue is a single problem found while validating a flow. NodeIDs and
+// EdgeID let the editor highlight the offending elements.
+type FlowIssue struct {
+	Severity string       `json:"severity"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
+	NodeIDs  []string     `json:"nodeIds,omitempty"`
+	EdgeID   string       `json:"edgeId,omitempty"`
+	Fields   []FieldError `json:"fields,omitempty"`
+}
+
+// ValidationReport is the result of ValidateFlow.
+type ValidationReport struct {
+	Valid  bool        `json:"valid"`
+	Issues []FlowIssue `json:"issues"`
+}
+
+// Errors returns only the issues that block execution.
+func (r ValidationReport) Errors() []FlowIssue {
+	var out []FlowIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// Err summarises the blocking issues as a single error, or returns nil.
+func (r ValidationReport) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, issue := range errs {
+		msgs[i] = issue.Message
+	}
+	return fmt.Errorf("invalid flowchart: %s", strings.Join(msgs, "; "))
+}
+
+func (r *ValidationReport) add(severity, code string, nodeIDs []string, edgeID, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, FlowIssue{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		NodeIDs:  nodeIDs,
+		EdgeID:   edgeID,
+	})
+}
+
+// validateFlow runs every pre-flight check on flow. It never stops at the
+// first problem so the editor can show them all at once.
+func validateFlow(flow FlowData) ValidationReport {
+	report := ValidationReport{Issues: []FlowIssue{}}
+
+	if len(flow.Nodes) == 0 {
+		report.add(SeverityError, "no-nodes", nil, "", "flowchart must contain at least one node")
+		return report
+	}
+
+	nodes := make(map[string]Node, len(flow.Nodes))
+	var startIDs []string
+	for _, node := range flow.Nodes {
+		if _, dup := nodes[node.ID]; dup {
+			report.add(SeverityError, "duplicate-node", []string{node.ID}, "", "node ID %s is used more than once", node.ID)
+			continue
+		}
+		nodes[node.ID] = node
+		if node.Type == "StartNode" {
+			startIDs = append(startIDs, node.ID)
+		}
+	}
+
+	switch {
+	case len(startIDs) == 0:
+		report.add(SeverityError, "no-start", nil, "", "no Start node found in flowchart")
+	case len(startIDs) > 1:
+		report.add(SeverityError, "multiple-starts", startIDs, "", "flowchart has %d Start nodes, expected exactly one", len(startIDs))
+	}
+
+	// Edges must reference existing nodes
+	connected := make(map[string]bool)
+	adjacency := make(map[string][]string)
+	var edges []Edge
+	for _, edge := range flow.Edges {
+		_, sourceOK := nodes[edge.Source]
+		_, targetOK := nodes[edge.Target]
+		if !sourceOK {
+			report.add(SeverityError, "missing-source", nil, edge.ID, "edge %s starts at missing node %s", edge.ID, edge.Source)
+		}
+		if !targetOK {
+			report.add(SeverityError, "missing-target", nil, edge.ID, "edge %s points to missing node %s", edge.ID, edge.Target)
+		}
+		if !sourceOK || !targetOK {
+			continue
+		}
+		connected[edge.Source] = true
+		connected[edge.Target] = true
+		adjacency[edge.Source] = append(adjacency[edge.Source], edge.Target)
+		edges = append(edges, edge)
+
+		// Nodes with labelled outputs only understand their own handles, and
+		// only nodes that route their errors have an error handle
+		if edge.SourceHandle == HandleError {
+			if nodeErrorPolicy(nodes[edge.Source]).Action != OnErrorRoute {
+				report.add(SeverityError, "unused-error-handle", []string{edge.Source}, edge.ID, "edge %s leaves node %s from the error handle, but its onError.action is not %q", edge.ID, edge.Source, OnErrorRoute)
+			}
+		} else if handles := handlesOf(nodes[edge.Source]); handles != nil && !slices.Contains(handles, edge.SourceHandle) {
+			report.add(SeverityError, "unknown-handle", []string{edge.Source}, edge.ID, "edge %s leaves node %s from unknown handle %q, expected one of %s", edge.ID, edge.Source, edge.SourceHandle, strings.Join(handles, ", "))
+		}
+	}
+
+	reachable := make(map[string]bool)
+	if len(startIDs) > 0 {
+		reachable = reachableFrom(startIDs[0], adjacency)
+	}
+
+	// Nodes outside the reachable set never run
+	var orphaned, unreachable []string
+	for _, node := range flow.Nodes {
+		switch {
+		case reachable[node.ID]:
+		case !connected[node.ID] && node.Type != "StartNode":
+			orphaned = append(orphaned, node.ID)
+		case len(startIDs) > 0:
+			unreachable = append(unreachable, node.ID)
+		}
+	}
+	if len(orphaned) > 0 {
+		report.add(SeverityWarning, "orphaned", orphaned, "", "%d node(s) have no connections and will be skipped: %s", len(orphaned), strings.Join(orphaned, ", "))
+	}
+	if len(unreachable) > 0 {
+		report.add(SeverityWarning, "unreachable", unreachable, "", "%d node(s) cannot be reached from the Start node: %s", len(unreachable), strings.Join(unreachable, ", "))
+	}
+
+	// A loop body may lead back to its LoopNode; any other cycle never ends
+	forward := validateLoops(&report, flow.Nodes, edges, reachable)
+	for _, cycle := range findCycles(reachable, forward) {
+		report.add(SeverityError, "cycle", cycle, "", "cycle detected: %s", strings.Join(append(cycle, cycle[0]), " → "))
+	}
+
+	// Per-node type and data checks; only nodes that will run can block execution
+	for _, node := range flow.Nodes {
+		severity := SeverityError
+		if !reachable[node.ID] {
+			severity = SeverityWarning
+		}
+		if _, ok := lookupExecutor(node.Type); !ok {
+			report.add(severity, "unknown-type", []string{node.ID}, "", "node %s has unknown type %q", node.ID, node.Type)
+			continue
+		}
+		if err := validateNode(node); err != nil {
+			issue := FlowIssue{
+				Severity: severity,
+				Code:     "invalid-data",
+				Message:  err.Error(),
+				NodeIDs:  []string{node.ID},
+			}
+			var fieldErrs FieldErrors
+			if errors.As(err, &fieldErrs) {
+				issue.Fields = fieldErrs
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	validateVariables(&report, flow.Nodes, reachable)
+
+	report.Valid = len(report.Errors()) == 0
+	return report
+}
+
+// validateVariables warns about templates in reachable nodes naming a
+// variable that no node in the flow sets. Such a template fails its node,
+// unless the flow is called with the variable as an input.
+func validateVariables(report *ValidationReport, nodes []Node, reachable map[string]bool) {
+	assigned := make(map[string]bool)
+	for _, name := range builtinVariables {
+		assigned[name] = true
+	}
+	for _, node := range nodes {
+		for _, name := range assignsOf(node) {
+			if hasTemplate(name) {
+				// The name is only known once the run expands it
+				continue
+			}
+			assigned[name] = true
+		}
+	}
+
+	var names []string
+	usedBy := make(map[string][]string)
+	for _, node := range nodes {
+		if !reachable[node.ID] {
+			continue
+		}
+		for _, s := range dataStrings(node.Data) {
+			for _, name := range templateNames(s) {
+				if assigned[name] || slices.Contains(usedBy[name], node.ID) {
+					continue
+				}
+				if usedBy[name] == nil {
+					names = append(names, name)
+				}
+				usedBy[name] = append(usedBy[name], node.ID)
+			}
+		}
+	}
+	for _, name := range names {
+		report.add(SeverityWarning, "undefined-variable", usedBy[name], "", "no node sets variable %q used by %s", name, strings.Join(usedBy[name], ", "))
+	}
+}
+
+// dataStrings returns every string within node data, in a stable order.
+func dataStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case map[string]interface{}:
+		keys := mapKeys(t)
+		sort.Strings(keys)
+		var out []string
+		for _, k := range keys {
+			out = append(out, dataStrings(t[k])...)
+		}
+		return out
+	case []interface{}:
+		var out []string
+		for _, item := range t {
+			out = append(out, dataStrings(item)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// validateLoops checks the structure of every reachable LoopNode and returns
+// the adjacency of edges without those leading from a loop body back to its
+// loop, which are the only cycles allowed.
+func validateLoops(report *ValidationReport, nodes []Node, edges []Edge, reachable map[string]bool) map[string][]string {
+	bodies := make(map[string]map[string]bool)
+	var loopIDs []string
+	for _, node := range nodes {
+		if node.Type == "LoopNode" && reachable[node.ID] {
+			if _, dup := bodies[node.ID]; !dup {
+				bodies[node.ID] = loopBranch(node.ID, HandleLoopBody, edges)
+				loopIDs = append(loopIDs, node.ID)
+			}
+		}
+	}
+
+	forward := make(map[string][]string)
+	for _, edge := range edges {
+		if body, isLoop := bodies[edge.Target]; isLoop && body[edge.Source] {
+			continue
+		}
+		forward[edge.Source] = append(forward[edge.Source], edge.Target)
+	}
+
+	for i, id := range loopIDs {
+		body := bodies[id]
+		// The body repeats on every iteration, so the exit path can't feed it
+		exit := loopBranch(id, HandleLoopExit, edges)
+		var shared []string
+		for bodyID := range body {
+			if exit[bodyID] {
+				shared = append(shared, bodyID)
+			}
+		}
+		if len(shared) > 0 {
+			sort.Strings(shared)
+			report.add(SeverityError, "loop-exit-in-body", append([]string{id}, shared...), "", "loop %s: node(s) %s can be reached from both its body and its exit", id, strings.Join(shared, ", "))
+		}
+		// Loops may nest, but can't be inside each other's bodies
+		for _, other := range loopIDs[i+1:] {
+			if body[other] && bodies[other][id] {
+				report.add(SeverityError, "loop-overlap", []string{id, other}, "", "loops %s and %s overlap: each is inside the other's body", id, other)
+			}
+		}
+	}
+	return forward
+}
+
+// reachableFrom returns every node reachable from start, including start.
+func reachableFrom(start string, adjacency map[string][]string) map[string]bool {
+	seen := map[string]bool{start: true}
+	stack := []string{start}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range adjacency[id] {
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return seen
+}
+
+// findCycles returns one node path per back edge found by a depth-first
+// search over the given node set.
+func findCycles(nodeSet map[string]bool, adjacency map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = inProgress
+		stack = append(stack, id)
+		for _, next := range adjacency[id] {
+			if !nodeSet[next] {
+				continue
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case inProgress:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == next {
+						cycles = append(cycles, append([]string(nil), stack[i:]...))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	// Visit in a stable order so reports don't shuffle between calls
+	ids := make([]string, 0, len(nodeSet))
+	for id := range nodeSet {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,112 @@
+// validate_test.go
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateFlowGraph(t *testing.T) {
+	tap := func(id string) testNode { return node(id, "KeyTap", `{"key":"a"}`) }
+	loop := node("loop", "LoopNode", `{"mode":"count","count":2}`)
+	ifNode := node("if", "IfNode", `{"condition":{"variable":"x","operator":"==","value":1}}`)
+	routed := node("a", "KeyTap", `{"key":"a","onError":{"action":"error"}}`)
+
+	tests := []struct {
+		name string
+		flow string
+		want []string // "severity code [node IDs] edge ID" of each issue
+	}{
+		{
+			name: "valid",
+			flow: graph([]testNode{tap("a"), tap("b")}, "start->a", "a->b"),
+		},
+		{
+			name: "cycle",
+			flow: graph([]testNode{tap("a"), tap("b"), tap("c")}, "start->a", "a->b", "b->c", "c->a"),
+			want: []string{"error cycle [a b c] "},
+		},
+		{
+			name: "self loop",
+			flow: graph([]testNode{tap("a")}, "start->a", "a->a"),
+			want: []string{"error cycle [a] "},
+		},
+		{
+			name: "loop body back to its loop",
+			flow: graph([]testNode{loop, tap("a"), tap("b")}, "start->loop", "loop:body->a", "a->loop", "loop:exit->b"),
+		},
+		{
+			name: "cycle within a loop body",
+			flow: graph([]testNode{loop, tap("a"), tap("b")}, "start->loop", "loop:body->a", "a->b", "b->a"),
+			want: []string{"error cycle [a b] "},
+		},
+		{
+			name: "loop exit leads into its body",
+			flow: graph([]testNode{loop, tap("a"), tap("b")}, "start->loop", "loop:body->a", "a->b", "loop:exit->b"),
+			want: []string{"error loop-exit-in-body [loop b] "},
+		},
+		{
+			name: "unknown handle",
+			flow: graph([]testNode{ifNode, tap("a")}, "start->if", "if:maybe->a"),
+			want: []string{"error unknown-handle [if] e1"},
+		},
+		{
+			name: "unknown loop handle",
+			flow: graph([]testNode{loop, tap("a")}, "start->loop", "loop:done->a"),
+			want: []string{"error unknown-handle [loop] e1"},
+		},
+		{
+			name: "error handle without the error policy",
+			flow: graph([]testNode{tap("a"), tap("b")}, "start->a", "a:error->b"),
+			want: []string{"error unused-error-handle [a] e1"},
+		},
+		{
+			name: "routed error handle",
+			flow: graph([]testNode{routed, tap("b")}, "start->a", "a:error->b"),
+		},
+		{
+			name: "missing nodes",
+			flow: graph([]testNode{tap("a")}, "start->a", "a->gone", "gone->a"),
+			want: []string{"error missing-target [] e1", "error missing-source [] e2"},
+		},
+		{
+			name: "multiple starts",
+			flow: graph([]testNode{node("start-2", "StartNode", `{}`), tap("a")}, "start->a", "start-2->a"),
+			want: []string{"error multiple-starts [start start-2] ", "warning unreachable [start-2] "},
+		},
+		{
+			name: "orphaned and unreachable",
+			flow: graph([]testNode{tap("a"), tap("b"), tap("c")}, "start->a", "b->c"),
+			want: []string{"warning unreachable [b c] "},
+		},
+		{
+			name: "unconnected",
+			flow: graph([]testNode{tap("a"), tap("b")}, "start->a"),
+			want: []string{"warning orphaned [b] "},
+		},
+		{
+			name: "bad data only warns where it can't run",
+			flow: graph([]testNode{tap("a"), node("bad", "KeyTap", `{"key":7}`), node("worse", "KeyTap", `{"key":7}`)},
+				"start->a", "a->bad", "worse->worse"),
+			want: []string{"warning unreachable [worse] ", "error invalid-data [bad] ", "warning invalid-data [worse] "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := validateFlow(parseFlow(t, tt.flow))
+			var got []string
+			for _, issue := range report.Issues {
+				got = append(got, fmt.Sprintf("%s %s %v %s", issue.Severity, issue.Code, issue.NodeIDs, issue.EdgeID))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("issues:\n got  %q\n want %q", got, tt.want)
+			}
+			if valid := !slices.ContainsFunc(tt.want, func(s string) bool { return strings.HasPrefix(s, "error ") }); report.Valid != valid {
+				t.Errorf("Valid = %v, want %v", report.Valid, valid)
+			}
+		})
+	}
+}
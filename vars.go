@@ -0,0 +1,220 @@
+// vars.go
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+func init() {
+	RegisterExecutor("SetVariableNode", setVariableExecutor{})
+}
+
+// variableStore holds the variables of a run. Nodes running on different
+// workers may read and write it at the same time.
+type variableStore struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+func newVariableStore() *variableStore {
+	return &variableStore{values: make(map[string]interface{})}
+}
+
+// Get returns the value of name and whether it is set.
+func (s *variableStore) Get(name string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// Set assigns value to name.
+func (s *variableStore) Set(name string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[name] = value
+}
+
+// Update assigns name the value update returns for its current one, with
+// no other write in between, so parallel branches incrementing the same
+// variable don't lose counts. If update fails, name is left unchanged.
+func (s *variableStore) Update(name string, update func(old interface{}, ok bool) (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.values[name]
+	value, err := update(old, ok)
+	if err != nil {
+		return nil, err
+	}
+	s.values[name] = value
+	return value, nil
+}
+
+//=============================================== Templates ===============================================
+
+// templatePattern matches a {{name}} template, or \{{ which stands for a
+// literal {{.
+var templatePattern = regexp.MustCompile(`\\\{\{|\{\{\s*([\w.-]+)\s*\}\}`)
+
+// builtinVariables are provided by the engine and can't be assigned.
+var builtinVariables = []string{"iteration", "run_id", "mouse.x", "mouse.y"}
+
+// templateNames returns the variable names of the templates in s.
+func templateNames(s string) []string {
+	var names []string
+	for _, m := range templatePattern.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// hasTemplate reports whether s contains a {{name}} template.
+func hasTemplate(s string) bool {
+	return len(templateNames(s)) > 0
+}
+
+// expandTemplate replaces every {{name}} in s with the value lookup returns,
+// and \{{ with {{. Unknown names are an error.
+func expandTemplate(s string, lookup func(name string) (interface{}, bool)) (string, error) {
+	var err error
+	out := templatePattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := templatePattern.FindStringSubmatch(match)[1]
+		if name == "" {
+			return "{{"
+		}
+		v, ok := lookup(name)
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("unknown variable %q in %q", name, s)
+			}
+			return match
+		}
+		return fmt.Sprint(v)
+	})
+	return out, err
+}
+
+// expandValue expands templates in every string within v, copying maps and
+// slices rather than modifying the node's data.
+func expandValue(v interface{}, lookup func(name string) (interface{}, bool)) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return expandTemplate(t, lookup)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			expanded, err := expandValue(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			expanded, err := expandValue(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+//=============================================== Set Variable ===============================================
+
+// SetVariableData is the data of a SetVariableNode.
+type SetVariableData struct {
+	Name      string      `json:"name"`
+	Operation string      `json:"operation"` // "set" or "increment"
+	Value     interface{} `json:"value"`     // for set; strings may use templates
+	Amount    float64     `json:"amount"`    // for increment
+}
+
+func decodeSetVariableData(data map[string]interface{}) (SetVariableData, error) {
+	r := newDataReader(data).AllowTemplates()
+	r.Require("name", "string")
+	d := SetVariableData{
+		Name:      r.String("name", ""),
+		Operation: r.OneOf("operation", "set", "set", "increment"),
+		Value:     r.Value("value"),
+		Amount:    r.Float("amount", 1),
+	}
+	switch {
+	case r.Has("name") && d.Name == "":
+		r.Fail("name", "non-empty string", d.Name, "")
+	case slices.Contains(builtinVariables, d.Name):
+		r.Fail("name", "variable name", d.Name, fmt.Sprintf("%q is a built-in variable and can't be assigned", d.Name))
+	}
+	return d, r.Err()
+}
+
+type setVariableExecutor struct{}
+
+func (setVariableExecutor) Validate(node Node) error {
+	_, err := decodeSetVariableData(node.Data)
+	return err
+}
+
+func (setVariableExecutor) Assigns(node Node) []string {
+	d, _ := decodeSetVariableData(node.Data)
+	return []string{d.Name}
+}
+
+func (setVariableExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSetVariableData(data)
+}
+
+func (setVariableExecutor) Describe(node Node) string {
+	d, _ := decodeSetVariableData(node.Data)
+	if d.Operation == "increment" {
+		return fmt.Sprintf("Add %v to %s", d.Amount, d.Name)
+	}
+	return fmt.Sprintf("Set %s to %v", d.Name, d.Value)
+}
+
+func (setVariableExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeSetVariableData(data)
+	if err != nil {
+		return err
+	}
+
+	value := d.Value
+	if d.Operation == "increment" {
+		value, err = ec.run.vars.Update(d.Name, func(old interface{}, ok bool) (interface{}, error) {
+			current := 0.0
+			if ok {
+				if current, ok = toNumber(old); !ok {
+					return nil, fmt.Errorf("variable %q is %v, not a number", d.Name, old)
+				}
+			}
+			return current + d.Amount, nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		ec.SetVariable(d.Name, value)
+	}
+	log.Printf("Set variable %s to %v", d.Name, value)
+	ec.Emit("variable-set", map[string]interface{}{"name": d.Name, "value": value})
+	return nil
+}
@@ -0,0 +1,140 @@
+// vars_test.go
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]interface{}{"name": "Ada", "n": 3}
+	lookup := func(name string) (interface{}, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"Hi {{name}}", "Hi Ada"},
+		{"{{ n }}{{n}}", "33"},
+		{`\{{name}}`, "{{name}}"},
+		{`if (a) \{{ b() }}`, "if (a) {{ b() }}"},
+		{`\{{unknown}} and {{name}}`, "{{unknown}} and Ada"},
+		{"{not a template}", "{not a template}"},
+	}
+	for _, tt := range tests {
+		got, err := expandTemplate(tt.in, lookup)
+		if err != nil || got != tt.want {
+			t.Errorf("expandTemplate(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := expandTemplate("{{unknown}}", lookup); err == nil || !strings.Contains(err.Error(), `unknown variable "unknown"`) {
+		t.Errorf("unknown variable: got %v", err)
+	}
+	if hasTemplate(`\{{name}}`) {
+		t.Error(`hasTemplate(\{{name}}) = true, want false`)
+	}
+}
+
+func TestUndefinedVariableWarning(t *testing.T) {
+	report := validateFlow(parseFlow(t, chain(
+		node("set", "SetVariableNode", `{"name":"count","operation":"increment"}`),
+		node("a", "TypeString", `{"text":"{{count}} {{missing}} {{iteration}} \\{{literal}}"}`),
+		node("b", "DelayNode", `{"time":"{{missing}}"}`),
+		node("c", "MouseMoveNode", `{"endPosition":{"type":"Fixed","coordinates":{"x":"{{match.x}}","y":"{{other}}"}}}`),
+	)))
+	var got []string
+	for _, issue := range report.Issues {
+		if issue.Code == "undefined-variable" {
+			if issue.Severity != SeverityWarning {
+				t.Errorf("%s: severity %s, want warning", issue.Message, issue.Severity)
+			}
+			got = append(got, fmt.Sprintf("%s %v", issue.Message, issue.NodeIDs))
+		}
+	}
+	want := []string{
+		`no node sets variable "missing" used by a, b [a b]`,
+		`no node sets variable "match.x" used by c [c]`,
+		`no node sets variable "other" used by c [c]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings:\n got  %q\n want %q", got, want)
+	}
+	if !report.Valid {
+		t.Errorf("unset variables should not block the run: %+v", report.Errors())
+	}
+}
+
+func TestUndefinedVariableWarningWithTemplatedName(t *testing.T) {
+	// A name only known at run time doesn't hide the other unset variables
+	report := validateFlow(parseFlow(t, chain(
+		node("key", "SetVariableNode", `{"name":"key","value":"total"}`),
+		node("set", "SetVariableNode", `{"name":"{{key}}","value":1}`),
+		node("a", "TypeString", `{"text":"{{key}} {{missing}}"}`),
+	)))
+	var got []string
+	for _, issue := range report.Issues {
+		if issue.Code == "undefined-variable" {
+			got = append(got, issue.Message)
+		}
+	}
+	if want := []string{`no node sets variable "missing" used by a`}; !slices.Equal(got, want) {
+		t.Errorf("warnings:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestVariableStoreUpdate(t *testing.T) {
+	s := newVariableStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Update("n", func(old interface{}, ok bool) (interface{}, error) {
+					n, _ := old.(int)
+					return n + 1, nil
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := s.Get("n"); v != 2000 {
+		t.Errorf("n = %v after 2000 increments", v)
+	}
+
+	if _, err := s.Update("n", func(interface{}, bool) (interface{}, error) {
+		return nil, fmt.Errorf("no")
+	}); err == nil {
+		t.Error("Update did not return the error")
+	}
+	if v, _ := s.Get("n"); v != 2000 {
+		t.Errorf("failed Update changed n to %v", v)
+	}
+}
+
+func TestParallelIncrements(t *testing.T) {
+	const branches = 20
+	nodes := []string{
+		`{"id":"start","type":"StartNode","data":{}}`,
+		`{"id":"out","type":"TypeString","data":{"text":"{{count}}"}}`,
+	}
+	var edges []string
+	for i := 0; i < branches; i++ {
+		id := fmt.Sprintf("inc%d", i)
+		nodes = append(nodes, fmt.Sprintf(`{"id":%q,"type":"SetVariableNode","data":{"name":"count","operation":"increment"}}`, id))
+		edges = append(edges,
+			fmt.Sprintf(`{"id":"s-%s","source":"start","target":%q}`, id, id),
+			fmt.Sprintf(`{"id":"%s-out","source":%q,"target":"out"}`, id, id))
+	}
+	flow := fmt.Sprintf(`{"nodes":[%s],"edges":[%s],"options":{"policy":"parallel"}}`,
+		strings.Join(nodes, ","), strings.Join(edges, ","))
+
+	got := runFlow(t, flow)
+	assertActions(t, got, fmt.Sprintf("type(%d)", branches))
+}
@@ -0,0 +1,261 @@
+// window.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+func init() {
+	RegisterExecutor("WindowNode", windowExecutor{})
+}
+
+// WindowInfo describes a window.
+type WindowInfo struct {
+	Title   string `json:"title"`
+	Process string `json:"process"` // executable name, e.g. "notepad.exe"
+}
+
+// WindowProvider reports which window has focus. Drivers that can tell
+// implement it alongside InputDriver.
+type WindowProvider interface {
+	ActiveWindow() (WindowInfo, error)
+}
+
+func (robotgoDriver) ActiveWindow() (WindowInfo, error) {
+	info := WindowInfo{Title: robotgo.GetTitle()}
+	name, err := robotgo.FindName(robotgo.GetPid())
+	if err != nil {
+		return info, fmt.Errorf("finding the process of the active window: %w", err)
+	}
+	info.Process = name
+	return info, nil
+}
+
+// SetActiveWindow sets the window ActiveWindow reports.
+func (d *RecordingDriver) SetActiveWindow(w WindowInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = w
+}
+
+func (d *RecordingDriver) ActiveWindow() (WindowInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.window, nil
+}
+
+//=============================================== Matching ===============================================
+
+// WindowMatch selects windows by title and/or process. Empty fields match
+// any window.
+type WindowMatch struct {
+	Title   string `json:"title,omitempty"`
+	Process string `json:"process,omitempty"`
+	// Match is how Title and Process are compared: "contains" and "exact"
+	// ignore case, "regex" uses Go regular expressions as written.
+	Match string `json:"match,omitempty"`
+}
+
+// readWindowMatch decodes the window fields of r.
+func readWindowMatch(r *dataReader) WindowMatch {
+	m := WindowMatch{
+		Title:   r.String("title", ""),
+		Process: r.String("process", ""),
+		Match:   r.OneOf("match", "contains", "contains", "exact", "regex"),
+	}
+	if m.Title == "" && m.Process == "" {
+		r.Fail("title", "non-empty string", m.Title, "set title or process")
+	}
+	if m.Match == "regex" {
+		for _, f := range [][2]string{{"title", m.Title}, {"process", m.Process}} {
+			if _, err := regexp.Compile(f[1]); err != nil && !hasTemplate(f[1]) {
+				r.Fail(f[0], "regular expression", f[1], err.Error())
+			}
+		}
+	}
+	return m
+}
+
+// String renders the match for descriptions and logs.
+func (m WindowMatch) String() string {
+	var parts []string
+	if m.Title != "" {
+		parts = append(parts, fmt.Sprintf("title %s %q", m.Match, m.Title))
+	}
+	if m.Process != "" {
+		parts = append(parts, fmt.Sprintf("process %s %q", m.Match, m.Process))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Matches reports whether w satisfies every non-empty field of m.
+func (m WindowMatch) Matches(w WindowInfo) (bool, error) {
+	for _, f := range [][2]string{{m.Title, w.Title}, {m.Process, w.Process}} {
+		pattern, value := f[0], f[1]
+		if pattern == "" {
+			continue
+		}
+		var ok bool
+		switch m.Match {
+		case "exact":
+			ok = strings.EqualFold(value, pattern)
+		case "regex":
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return false, err
+			}
+			ok = re.MatchString(value)
+		default:
+			ok = strings.Contains(strings.ToLower(value), strings.ToLower(pattern))
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// activeWindow asks the run's window provider for the focused window.
+func activeWindow(ec *ExecContext) (WindowInfo, error) {
+	if ec.Windows == nil {
+		return WindowInfo{}, errors.New("window information is not available with this input driver")
+	}
+	return ec.Windows.ActiveWindow()
+}
+
+//=============================================== Window Node ===============================================
+
+// WindowData is the data of a WindowNode. Times are in milliseconds.
+type WindowData struct {
+	WindowMatch
+	Mode         string  `json:"mode"` // "wait" or "branch"
+	PollInterval float64 `json:"pollInterval"`
+	Timeout      float64 `json:"timeout"` // 0: wait forever (wait) or check once (branch)
+}
+
+func decodeWindowData(data map[string]interface{}) (WindowData, error) {
+	r := newDataReader(data).AllowTemplates()
+	d := WindowData{
+		WindowMatch:  readWindowMatch(r),
+		Mode:         r.OneOf("mode", "wait", "wait", "branch"),
+		PollInterval: r.Float("pollInterval", 100),
+	}
+	defaultTimeout := 10000.0
+	if d.Mode == "branch" {
+		defaultTimeout = 0
+	}
+	d.Timeout = r.Float("timeout", defaultTimeout)
+
+	r.Min("pollInterval", d.PollInterval, 1)
+	r.Min("timeout", d.Timeout, 0)
+	return d, r.Err()
+}
+
+// windowExecutor waits for a window to be focused or branches on whether it
+// is.
+type windowExecutor struct{}
+
+func (windowExecutor) Validate(node Node) error {
+	_, err := decodeWindowData(node.Data)
+	return err
+}
+
+func (windowExecutor) Handles(node Node) []string {
+	d, _ := decodeWindowData(node.Data)
+	if d.Mode != "branch" {
+		return nil
+	}
+	return []string{HandleMatch, HandleNoMatch}
+}
+
+func (windowExecutor) ResolveParams(ec *ExecContext) (interface{}, error) {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWindowData(data)
+}
+
+func (windowExecutor) Describe(node Node) string {
+	d, err := decodeWindowData(node.Data)
+	if err != nil {
+		return "Check the active window"
+	}
+	if d.Mode == "branch" {
+		return fmt.Sprintf("Branch on whether the active window has %s", d.WindowMatch)
+	}
+	return fmt.Sprintf("Wait for a window with %s", d.WindowMatch)
+}
+
+func (windowExecutor) Execute(ec *ExecContext) error {
+	data, err := ec.ExpandData(ec.Task.Data)
+	if err != nil {
+		return err
+	}
+	d, err := decodeWindowData(data)
+	if err != nil {
+		return err
+	}
+
+	matched, got, err := pollWindow(ec, d)
+	if err != nil {
+		return err
+	}
+	log.Printf("Active window is %q (%s), matched %v", got.Title, got.Process, matched)
+	ec.Emit("window-checked", map[string]interface{}{
+		"title":   got.Title,
+		"process": got.Process,
+		"matched": matched,
+	})
+
+	if d.Mode == "branch" {
+		handle := HandleNoMatch
+		if matched {
+			handle = HandleMatch
+		}
+		followBranch(ec, handle)
+		return nil
+	}
+	if !matched {
+		return fmt.Errorf("no window with %s was focused within %v ms (active: %q, %s)", d.WindowMatch, d.Timeout, got.Title, got.Process)
+	}
+	return nil
+}
+
+// pollWindow checks the active window until it matches or the timeout has
+// passed, returning the last window seen. Time spent paused doesn't count
+// towards the timeout.
+func pollWindow(ec *ExecContext, d WindowData) (bool, WindowInfo, error) {
+	timeout := time.Duration(d.Timeout * float64(time.Millisecond))
+	interval := time.Duration(d.PollInterval * float64(time.Millisecond))
+	var elapsed time.Duration
+	for {
+		start := time.Now()
+		got, err := activeWindow(ec)
+		if err != nil {
+			return false, got, err
+		}
+		matched, err := d.Matches(got)
+		if err != nil || matched {
+			return matched, got, err
+		}
+		elapsed += time.Since(start)
+
+		checkOnce := timeout == 0 && d.Mode == "branch"
+		if checkOnce || (timeout > 0 && elapsed >= timeout) {
+			return false, got, nil
+		}
+		if err := ec.Sleep(interval); err != nil {
+			return false, got, err
+		}
+		elapsed += interval
+	}
+}